@@ -1,39 +1,187 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"../../internal/config"
 	"../../internal/http"
 	"../../internal/http/api"
+	"../../internal/omdb"
+	"../../internal/refresh"
+	"../../internal/render"
 	"../../internal/sqlite"
 )
 
 func main() {
+	// Load configuration from flags, environment and file.
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+	if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		log.Fatal(err)
+	}
+
+	// Run the migrate subcommand instead of the server if requested.
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := migrate(cfg.DBPath, args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Run the user subcommand instead of the server if requested.
+	if len(args) > 0 && args[0] == "user" {
+		if err := user(cfg.DBPath, args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Run the token subcommand instead of the server if requested.
+	if len(args) > 0 && args[0] == "token" {
+		if err := token(cfg.DBPath, args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Load templates.
+	if err := render.Load(cfg.TemplateDir); err != nil {
+		log.Fatal(err)
+	}
+
 	// Start database.
-	db, err := sqlite.Start("./web/data/pmdb.db")
-	defer db.Close()
+	db, err := sqlite.Start(cfg.DBPath)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// Create services.
-	movieService := &sqlite.MovieService{DB: db}
+	movieService := &sqlite.MovieService{
+		DB:          db,
+		Timeout:     sqlite.Timeout(cfg.DBTimeout),
+		RatingScale: cfg.RatingScale,
+	}
+	metadataService := &omdb.Client{
+		BaseURL: cfg.OMDb.BaseURL,
+		APIKey:  cfg.OMDb.APIKey,
+	}
+	jobService := &sqlite.JobService{DB: db, Timeout: sqlite.Timeout(cfg.DBTimeout)}
+	userService := &sqlite.UserService{DB: db, Timeout: sqlite.Timeout(cfg.DBTimeout)}
+	tokenService := &sqlite.TokenService{DB: db, Timeout: sqlite.Timeout(cfg.DBTimeout)}
+	reviewService := &sqlite.ReviewService{
+		DB:      db,
+		Timeout: sqlite.Timeout(cfg.DBTimeout),
+		Scale:   cfg.RatingScale,
+	}
+	viewingService := &sqlite.ViewingService{DB: db, Timeout: sqlite.Timeout(cfg.DBTimeout)}
+	watchlistService := &sqlite.WatchlistService{DB: db, Timeout: sqlite.Timeout(cfg.DBTimeout)}
+	tagService := &sqlite.TagService{DB: db, Timeout: sqlite.Timeout(cfg.DBTimeout)}
+	collectionService := &sqlite.CollectionService{DB: db, Timeout: sqlite.Timeout(cfg.DBTimeout)}
+	copyService := &sqlite.CopyService{DB: db, Timeout: sqlite.Timeout(cfg.DBTimeout)}
+
+	// Stop the server when an interrupt or termination signal arrives.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start background work, which is stopped once the server exits.
+	workCtx, stopWork := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if metadataService.APIKey == "" {
+			return
+		}
+		refresher := &refresh.Refresher{
+			MovieService:    movieService,
+			MetadataService: metadataService,
+			JobService:      jobService,
+			Interval:        cfg.Refresh.Interval,
+			MaxAge:          cfg.Refresh.MaxAge,
+			Rate:            cfg.Refresh.Rate,
+			MaxRetries:      cfg.Refresh.MaxRetries,
+			Backoff:         cfg.Refresh.Backoff,
+		}
+		refresher.Run(workCtx)
+	}()
 
 	// Init handlers and attach services to handlers if necessary.
-	apiMovieHandler := &api.MovieHandler{MovieService: movieService}
-	movieHandler := &http.MovieHandler{MovieService: movieService}
+	apiMovieHandler := &api.MovieHandler{
+		MovieService:     movieService,
+		MetadataService:  metadataService,
+		ReviewService:    reviewService,
+		ViewingService:   viewingService,
+		WatchlistService: watchlistService,
+		CopyService:      copyService,
+	}
+	apiTrashHandler := &api.TrashHandler{MovieService: movieService}
+	apiDiaryHandler := &api.DiaryHandler{ViewingService: viewingService}
+	apiWatchlistHandler := &api.WatchlistHandler{WatchlistService: watchlistService}
+	apiTagHandler := &api.TagHandler{TagService: tagService}
+	apiCollectionHandler := &api.CollectionHandler{CollectionService: collectionService}
+	apiCopyHandler := &api.CopyHandler{CopyService: copyService}
+	movieHandler := &http.MovieHandler{
+		MovieService:  movieService,
+		ReviewService: reviewService,
+		CopyService:   copyService,
+	}
+	trashHandler := &http.TrashHandler{MovieService: movieService}
+	diaryHandler := &http.DiaryHandler{ViewingService: viewingService}
+	apiTokenAuth := &api.TokenAuth{TokenService: tokenService}
 	pageHandler := &http.PageHandler{}
+	tokenHandler := &http.TokenHandler{TokenService: tokenService}
+	sessionHandler := &http.SessionHandler{
+		UserService: userService,
+		MaxAge:      cfg.SessionMaxAge,
+		Secure:      cfg.TLSMode != config.TLSOff,
+	}
 
 	// Attach handlers to router.
 	router := &http.Router{
-		APIMovieHandler: apiMovieHandler,
-		MovieHandler:    movieHandler,
-		PageHandler:     pageHandler,
+		APIMovieHandler:      apiMovieHandler,
+		APITrashHandler:      apiTrashHandler,
+		APIDiaryHandler:      apiDiaryHandler,
+		APIWatchlistHandler:  apiWatchlistHandler,
+		APITagHandler:        apiTagHandler,
+		APICollectionHandler: apiCollectionHandler,
+		APICopyHandler:       apiCopyHandler,
+		MovieHandler:         movieHandler,
+		TrashHandler:         trashHandler,
+		DiaryHandler:         diaryHandler,
+		PageHandler:          pageHandler,
+		SessionHandler:       sessionHandler,
+		TokenHandler:         tokenHandler,
+		APITokenAuth:         apiTokenAuth,
 	}
 
 	// Create a server.
-	srv := &http.Server{Router: router.Router()}
+	srv := &http.Server{
+		Router:    router.Router(),
+		HTTPAddr:  cfg.HTTPAddr,
+		HTTPSAddr: cfg.HTTPSAddr,
+		CertFile:  cfg.CertFile,
+		KeyFile:   cfg.KeyFile,
 
-	// Run the server.
-	log.Fatal(srv.Run())
+		PlainHTTP:       cfg.TLSMode == config.TLSOff,
+		SelfSigned:      cfg.TLSMode == config.TLSSelfSigned,
+		ShutdownTimeout: cfg.ShutdownTimeout,
+	}
+
+	// Run the server until it fails or a signal arrives, then wait for
+	// background work to stop before closing the database.
+	err = srv.Run(ctx)
+	stopWork()
+	<-done
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"../../internal/sqlite"
+)
+
+// migrate runs the migrate subcommand against the database at path.
+// It supports the "status", "up" and "down" actions.
+func migrate(path string, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: pmdb [flags] migrate status|up|down")
+	}
+
+	// Open the database without applying migrations.
+	db, err := sqlite.Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "status":
+		statuses, err := sqlite.Migrations(db)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied() {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return tw.Flush()
+	case "up":
+		return sqlite.MigrateUp(db)
+	case "down":
+		return sqlite.MigrateDown(db)
+	default:
+		return fmt.Errorf("unknown migrate action %q", args[0])
+	}
+}
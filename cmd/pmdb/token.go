@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"../../internal/service"
+	"../../internal/sqlite"
+)
+
+// token runs the token subcommand against the database at path. It
+// supports the "add", "list" and "revoke" actions for the personal access
+// tokens of a user.
+func token(path string, args []string) error {
+	usage := errors.New("usage: pmdb [flags] token add USERNAME NAME read|write [EXPIRES] | list USERNAME | revoke USERNAME ID")
+	if len(args) < 2 {
+		return usage
+	}
+
+	// Open the database, applying any pending migrations.
+	db, err := sqlite.Start(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tokenService := &sqlite.TokenService{DB: db}
+
+	u, err := (&sqlite.UserService{DB: db}).GetUserByUsername(ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "add" && (len(args) == 4 || len(args) == 5):
+		t := &service.Token{UserID: u.ID, Name: args[2], Scope: args[3]}
+		if len(args) == 5 {
+			d, err := time.ParseDuration(args[4])
+			if err != nil {
+				return err
+			}
+			expiresAt := time.Now().Add(d)
+			t.ExpiresAt = &expiresAt
+		}
+
+		secret, err := tokenService.CreateToken(ctx, t)
+		for field, message := range service.ErrorFields(err) {
+			err = fmt.Errorf("%v; %s %s", err, field, message)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(secret)
+		return nil
+	case args[0] == "list" && len(args) == 2:
+		tokens, err := tokenService.GetTokens(ctx, u.ID)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tSCOPE\tEXPIRES\tSTATUS")
+		for _, t := range *tokens {
+			expires, status := "never", "active"
+			if t.ExpiresAt != nil {
+				expires = t.ExpiresAt.Format("2006-01-02 15:04:05")
+			}
+			if t.RevokedAt != nil {
+				status = "revoked"
+			} else if !t.Active(time.Now()) {
+				status = "expired"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Prefix, t.Scope, expires, status)
+		}
+		return tw.Flush()
+	case args[0] == "revoke" && len(args) == 3:
+		id, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return usage
+		}
+		return tokenService.RevokeToken(ctx, u.ID, id)
+	default:
+		return usage
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"../../internal/service"
+	"../../internal/sqlite"
+)
+
+// user runs the user subcommand against the database at path. It
+// supports the "add" and "passwd" actions, which read the password from
+// standard input.
+func user(path string, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: pmdb [flags] user add|passwd USERNAME")
+	}
+
+	// Open the database, applying any pending migrations.
+	db, err := sqlite.Start(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	userService := &sqlite.UserService{DB: db}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return errors.New("no password given")
+	}
+	password = strings.TrimRight(password, "\r\n")
+
+	switch args[0] {
+	case "add":
+		_, err = userService.CreateUser(ctx, args[1], password)
+	case "passwd":
+		var u *service.User
+		if u, err = userService.GetUserByUsername(ctx, args[1]); err == nil {
+			err = userService.SetPassword(ctx, u.ID, password)
+		}
+	default:
+		return fmt.Errorf("unknown user action %q", args[0])
+	}
+
+	// Field errors are not part of the error message, so list them.
+	for field, message := range service.ErrorFields(err) {
+		err = fmt.Errorf("%v; %s %s", err, field, message)
+	}
+
+	return err
+}
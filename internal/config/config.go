@@ -0,0 +1,252 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TLS modes.
+const (
+	TLSFile       = "file"        // Serve HTTPS with CertFile and KeyFile.
+	TLSSelfSigned = "self-signed" // As TLSFile, generating the pair if needed.
+	TLSOff        = "off"         // Serve plain HTTP only.
+)
+
+// Config is a struct containing the settings needed to run the server.
+type Config struct {
+	HTTPAddr        string        `toml:"http_addr"`
+	HTTPSAddr       string        `toml:"https_addr"`
+	TLSMode         string        `toml:"tls_mode"`
+	CertFile        string        `toml:"cert_file"`
+	KeyFile         string        `toml:"key_file"`
+	ShutdownTimeout time.Duration `toml:"shutdown_timeout"`
+	DBPath          string        `toml:"db_path"`
+	DBTimeout       time.Duration `toml:"db_timeout"`
+	TemplateDir     string        `toml:"template_dir"`
+	SessionMaxAge   time.Duration `toml:"session_max_age"`
+	RatingScale     int           `toml:"rating_scale"`
+
+	OMDb    OMDb    `toml:"omdb"`
+	Refresh Refresh `toml:"refresh"`
+}
+
+// OMDb is a struct containing the settings for the OMDb API client.
+type OMDb struct {
+	APIKey  string `toml:"api_key"`
+	BaseURL string `toml:"base_url"` // Defaults to the public OMDb API.
+}
+
+// Refresh is a struct containing the settings for the background
+// metadata refresh worker.
+type Refresh struct {
+	Interval   time.Duration `toml:"interval"`
+	MaxAge     time.Duration `toml:"max_age"`
+	Rate       time.Duration `toml:"rate"`
+	MaxRetries int           `toml:"max_retries"`
+	Backoff    time.Duration `toml:"backoff"`
+}
+
+// Default returns the configuration used for any setting that isn't
+// provided.
+func Default() *Config {
+	return &Config{
+		HTTPAddr:        ":8080",
+		HTTPSAddr:       ":8081",
+		TLSMode:         TLSFile,
+		CertFile:        "localhost.pem",
+		KeyFile:         "localhost-key.pem",
+		ShutdownTimeout: 15 * time.Second,
+		DBPath:          "./web/data/pmdb.db",
+		DBTimeout:       5 * time.Second,
+		TemplateDir:     "internal/templates/",
+		SessionMaxAge:   30 * 24 * time.Hour,
+		RatingScale:     10,
+		Refresh: Refresh{
+			Interval:   time.Hour,
+			MaxAge:     30 * 24 * time.Hour,
+			Rate:       time.Second,
+			MaxRetries: 3,
+			Backoff:    5 * time.Second,
+		},
+	}
+}
+
+// setting describes a configuration value that can be set by a command
+// line flag or an environment variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+// settings lists every value that can be set by flag or environment.
+var settings = []setting{
+	{"http-addr", "PMDB_HTTP_ADDR", "address for the HTTP server", str(func(c *Config) *string { return &c.HTTPAddr })},
+	{"https-addr", "PMDB_HTTPS_ADDR", "address for the HTTPS server", str(func(c *Config) *string { return &c.HTTPSAddr })},
+	{"tls-mode", "PMDB_TLS_MODE", "TLS mode: file, self-signed or off", str(func(c *Config) *string { return &c.TLSMode })},
+	{"cert-file", "PMDB_CERT_FILE", "TLS certificate file", str(func(c *Config) *string { return &c.CertFile })},
+	{"key-file", "PMDB_KEY_FILE", "TLS key file", str(func(c *Config) *string { return &c.KeyFile })},
+	{"shutdown-timeout", "PMDB_SHUTDOWN_TIMEOUT", "how long to wait for in-flight requests on shutdown", dur(func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+	{"db-path", "PMDB_DB_PATH", "SQLite database path", str(func(c *Config) *string { return &c.DBPath })},
+	{"db-timeout", "PMDB_DB_TIMEOUT", "maximum duration of a database call", dur(func(c *Config) *time.Duration { return &c.DBTimeout })},
+	{"template-dir", "PMDB_TEMPLATE_DIR", "directory containing the HTML templates", str(func(c *Config) *string { return &c.TemplateDir })},
+	{"session-max-age", "PMDB_SESSION_MAX_AGE", "how long a login session lasts", dur(func(c *Config) *time.Duration { return &c.SessionMaxAge })},
+	{"rating-scale", "PMDB_RATING_SCALE", "highest score of a movie review", integer(func(c *Config) *int { return &c.RatingScale })},
+	{"omdb-api-key", "OMDB_API_KEY", "OMDb API key", str(func(c *Config) *string { return &c.OMDb.APIKey })},
+	{"omdb-base-url", "OMDB_BASE_URL", "OMDb API base URL", str(func(c *Config) *string { return &c.OMDb.BaseURL })},
+	{"refresh-interval", "PMDB_REFRESH_INTERVAL", "how often to look for stale metadata", dur(func(c *Config) *time.Duration { return &c.Refresh.Interval })},
+	{"refresh-max-age", "PMDB_REFRESH_MAX_AGE", "age at which metadata is refreshed", dur(func(c *Config) *time.Duration { return &c.Refresh.MaxAge })},
+	{"refresh-rate", "PMDB_REFRESH_RATE", "minimum delay between OMDb requests", dur(func(c *Config) *time.Duration { return &c.Refresh.Rate })},
+	{"refresh-max-retries", "PMDB_REFRESH_MAX_RETRIES", "retries for a failed OMDb request", integer(func(c *Config) *int { return &c.Refresh.MaxRetries })},
+	{"refresh-backoff", "PMDB_REFRESH_BACKOFF", "initial delay before retrying an OMDb request", dur(func(c *Config) *time.Duration { return &c.Refresh.Backoff })},
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// the defaults, the TOML file named by the -config flag or PMDB_CONFIG
+// environment variable, environment variables and command line flags. It
+// returns the validated configuration and the remaining arguments.
+func Load(args []string, getenv func(string) string) (*Config, []string, error) {
+	c := Default()
+
+	// Flags are recorded while parsing and applied last so that they take
+	// precedence over the file and environment.
+	fs := flag.NewFlagSet("pmdb", flag.ContinueOnError)
+	path := fs.String("config", getenv("PMDB_CONFIG"), "TOML configuration file")
+	flags := make(map[string]string)
+	for _, s := range settings {
+		name := s.flag
+		fs.Func(name, s.usage+" (env "+s.env+")", func(v string) error {
+			flags[name] = v
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *path != "" {
+		if _, err := toml.DecodeFile(*path, c); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := s.set(c, v); err != nil {
+				return nil, nil, fmt.Errorf("%s: %v", s.env, err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := flags[s.flag]; ok {
+			if err := s.set(c, v); err != nil {
+				return nil, nil, fmt.Errorf("-%s: %v", s.flag, err)
+			}
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return c, fs.Args(), nil
+}
+
+// Validate returns an error if any setting is missing or invalid.
+func (c *Config) Validate() error {
+	for name, addr := range map[string]string{"http_addr": c.HTTPAddr, "https_addr": c.HTTPSAddr} {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid %s %q: %v", name, addr, err)
+		}
+	}
+
+	switch c.TLSMode {
+	case TLSFile, TLSSelfSigned, TLSOff:
+	default:
+		return fmt.Errorf("invalid tls_mode %q", c.TLSMode)
+	}
+
+	required := map[string]string{
+		"cert_file":    c.CertFile,
+		"key_file":     c.KeyFile,
+		"db_path":      c.DBPath,
+		"template_dir": c.TemplateDir,
+	}
+	for name, v := range required {
+		if v == "" {
+			return errors.New("missing " + name)
+		}
+	}
+
+	if c.OMDb.BaseURL != "" {
+		if u, err := url.Parse(c.OMDb.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid omdb.base_url %q", c.OMDb.BaseURL)
+		}
+	}
+
+	positive := map[string]time.Duration{
+		"shutdown_timeout": c.ShutdownTimeout,
+		"db_timeout":       c.DBTimeout,
+		"session_max_age":  c.SessionMaxAge,
+		"refresh.interval": c.Refresh.Interval,
+		"refresh.max_age":  c.Refresh.MaxAge,
+		"refresh.rate":     c.Refresh.Rate,
+		"refresh.backoff":  c.Refresh.Backoff,
+	}
+	for name, d := range positive {
+		if d <= 0 {
+			return fmt.Errorf("invalid %s %s: must be positive", name, d)
+		}
+	}
+
+	if c.RatingScale < 2 || c.RatingScale > 100 {
+		return fmt.Errorf("invalid rating_scale %d: must be between 2 and 100", c.RatingScale)
+	}
+
+	if c.Refresh.MaxRetries < 0 {
+		return fmt.Errorf("invalid refresh.max_retries %d: must not be negative", c.Refresh.MaxRetries)
+	}
+
+	return nil
+}
+
+// str returns a setter for a string setting.
+func str(field func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+// dur returns a setter for a duration setting, such as "5s".
+func dur(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+// integer returns a setter for an integer setting.
+func integer(field func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
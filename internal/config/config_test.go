@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// env returns a getenv function that looks variables up in vars.
+func env(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+func TestDefault(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Default().Validate() error = %v", err)
+	}
+
+	c, args, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(c, Default()) {
+		t.Errorf("Load() = %+v, want the defaults %+v", c, Default())
+	}
+	if len(args) != 0 {
+		t.Errorf("Load() args = %q, want none", args)
+	}
+	if c.TLSMode != TLSFile {
+		t.Errorf("Load() TLSMode = %q, want %q", c.TLSMode, TLSFile)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pmdb.toml")
+	file := `
+db_path = "file.db"
+db_timeout = "7s"
+
+[refresh]
+max_retries = 5
+`
+	if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		dbPath  string
+		timeout time.Duration
+		retries int
+		rest    []string
+	}{
+		{
+			name:    "file",
+			args:    []string{"-config", path},
+			dbPath:  "file.db",
+			timeout: 7 * time.Second,
+			retries: 5,
+		},
+		{
+			name:    "file from environment",
+			env:     map[string]string{"PMDB_CONFIG": path},
+			dbPath:  "file.db",
+			timeout: 7 * time.Second,
+			retries: 5,
+		},
+		{
+			name:    "environment over file",
+			args:    []string{"-config", path},
+			env:     map[string]string{"PMDB_DB_PATH": "env.db"},
+			dbPath:  "env.db",
+			timeout: 7 * time.Second,
+			retries: 5,
+		},
+		{
+			name:    "flag over environment",
+			args:    []string{"-config", path, "-db-path", "flag.db", "-db-timeout", "2s"},
+			env:     map[string]string{"PMDB_DB_PATH": "env.db", "PMDB_DB_TIMEOUT": "3s"},
+			dbPath:  "flag.db",
+			timeout: 2 * time.Second,
+			retries: 5,
+		},
+		{
+			name:    "remaining arguments",
+			args:    []string{"-db-path", "flag.db", "migrate", "up"},
+			dbPath:  "flag.db",
+			timeout: Default().DBTimeout,
+			retries: Default().Refresh.MaxRetries,
+			rest:    []string{"migrate", "up"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rest, err := Load(tt.args, env(tt.env))
+			if err != nil {
+				t.Fatalf("Load(%q) error = %v", tt.args, err)
+			}
+			if c.DBPath != tt.dbPath {
+				t.Errorf("DBPath = %q, want %q", c.DBPath, tt.dbPath)
+			}
+			if c.DBTimeout != tt.timeout {
+				t.Errorf("DBTimeout = %s, want %s", c.DBTimeout, tt.timeout)
+			}
+			if len(rest) != len(tt.rest) || len(rest) > 0 && !reflect.DeepEqual(rest, tt.rest) {
+				t.Errorf("args = %q, want %q", rest, tt.rest)
+			}
+
+			// Settings only in the file keep their file values, and
+			// settings in no source keep their defaults.
+			if c.HTTPAddr != Default().HTTPAddr {
+				t.Errorf("HTTPAddr = %q, want the default %q", c.HTTPAddr, Default().HTTPAddr)
+			}
+			if c.Refresh.MaxRetries != tt.retries {
+				t.Errorf("Refresh.MaxRetries = %d, want %d", c.Refresh.MaxRetries, tt.retries)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{"unknown flag", []string{"-nope"}, nil},
+		{"missing file", []string{"-config", filepath.Join(t.TempDir(), "missing.toml")}, nil},
+		{"bad tls mode", []string{"-tls-mode", "on"}, nil},
+		{"bad duration flag", []string{"-db-timeout", "soon"}, nil},
+		{"bad duration environment", nil, map[string]string{"PMDB_SHUTDOWN_TIMEOUT": "10"}},
+		{"zero duration", []string{"-refresh-rate", "0s"}, nil},
+		{"bad integer", nil, map[string]string{"PMDB_RATING_SCALE": "ten"}},
+		{"rating scale too small", []string{"-rating-scale", "1"}, nil},
+		{"negative retries", []string{"-refresh-max-retries", "-1"}, nil},
+		{"bad address", []string{"-http-addr", "8080"}, nil},
+		{"empty db path", []string{"-db-path", ""}, nil},
+		{"bad omdb url", []string{"-omdb-base-url", "omdbapi.com"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c, _, err := Load(tt.args, env(tt.env)); err == nil {
+				t.Errorf("Load(%q) = %+v, want an error", tt.args, c)
+			}
+		})
+	}
+}
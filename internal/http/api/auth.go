@@ -0,0 +1,76 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"../../render"
+	"../../service"
+)
+
+// TokenAuth checks the personal access tokens sent to the API.
+type TokenAuth struct {
+	TokenService service.TokenService
+}
+
+// Authenticate is middleware that requires a bearer token on every
+// request. Reading needs a token with the read or write scope and any
+// other method needs the write scope. The token's user is carried in the
+// request context and recorded as the actor of any changes.
+func (a *TokenAuth) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Parse the token from the Authorization header.
+		secret, ok := bearerToken(r)
+		if !ok {
+			err := service.Errorf(service.EUnauthorized, "a bearer token is required")
+			challenge(w, r, err, "")
+			return
+		}
+
+		// Call AuthenticateToken to look up the token and its user.
+		user, token, err := a.TokenService.AuthenticateToken(r.Context(), secret)
+		if err == service.ErrInvalidToken {
+			challenge(w, r, err, `, error="invalid_token"`)
+			return
+		} else if err != nil {
+			// Render an error response and set status code.
+			render.Error(w, r, err)
+			log.Println("Error:", err)
+			return
+		}
+
+		scope := service.ScopeWrite
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = service.ScopeRead
+		}
+		if !token.Allows(scope) {
+			err := service.Errorf(service.EForbidden, "the token does not have the %s scope", scope)
+			challenge(w, r, err, `, error="insufficient_scope", scope="`+scope+`"`)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(service.NewUserContext(r.Context(), user)))
+	})
+}
+
+// bearerToken returns the token from a "Bearer" Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	fields := strings.Fields(r.Header.Get("Authorization"))
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "Bearer") {
+		return "", false
+	}
+
+	return fields[1], true
+}
+
+// challenge renders err along with a WWW-Authenticate header asking for a
+// bearer token, as described by RFC 6750. params are added to the header.
+func challenge(w http.ResponseWriter, r *http.Request, err error, params string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="pmdb"`+params)
+
+	// Render an error response and set status code.
+	render.Error(w, r, err)
+	log.Println("Error:", err)
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"../../render"
+	"../../service"
+	"github.com/go-chi/chi"
+)
+
+// collectionsPath is where the collection handler routes are mounted.
+const collectionsPath = "/api/v1/collections"
+
+// CollectionHandler ...
+type CollectionHandler struct {
+	CollectionService service.CollectionService
+}
+
+// Routes creates a REST router for the collection handler.
+func (h *CollectionHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Load middleware specific to this router.
+	// r.Use()
+
+	r.Get("/", h.index)
+	r.Post("/", h.create)
+	r.Get("/{id}", h.show)
+	r.Put("/{id}", h.update)
+	r.Delete("/{id}", h.delete)
+	r.Put("/{id}/movies/{movieId}", h.add)
+	r.Delete("/{id}/movies/{movieId}", h.remove)
+
+	return r
+}
+
+// Index responds to a request for a list of collections.
+func (h *CollectionHandler) index(w http.ResponseWriter, r *http.Request) {
+	// Call GetCollections to retrieve all collections from the database.
+	if collections, err := h.CollectionService.GetCollections(r.Context()); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, collections)
+	}
+}
+
+// Create responds to a request for adding a collection.
+func (h *CollectionHandler) create(w http.ResponseWriter, r *http.Request) {
+	// Decode the request body into a temporary collection struct.
+	collection, err := decodeCollection(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call CreateCollection to add the new collection to the database.
+	if err := h.CollectionService.CreateCollection(r.Context(), collection); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		w.Header().Set("Location", collectionsPath+"/"+strconv.FormatInt(collection.ID, 10))
+		render.JSON(w, http.StatusCreated, collection)
+	}
+}
+
+// Show responds to a request for a single collection.
+func (h *CollectionHandler) show(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := collectionID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetCollection to get the collection from the database.
+	if collection, err := h.CollectionService.GetCollection(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, collection)
+	}
+}
+
+// Update responds to a request for changing a collection.
+func (h *CollectionHandler) update(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := collectionID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Decode the request body into a temporary collection struct.
+	collection, err := decodeCollection(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call UpdateCollection to change the collection in the database.
+	if err := h.CollectionService.UpdateCollection(r.Context(), id, collection); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, collection)
+	}
+}
+
+// Delete responds to a request for removing a collection.
+func (h *CollectionHandler) delete(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := collectionID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call DeleteCollection to remove the collection from the database.
+	if err := h.CollectionService.DeleteCollection(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, map[string]string{})
+	}
+}
+
+// Add responds to a request for adding a movie to a collection.
+func (h *CollectionHandler) add(w http.ResponseWriter, r *http.Request) {
+	h.member(w, r, h.CollectionService.AddMovie)
+}
+
+// Remove responds to a request for removing a movie from a collection.
+func (h *CollectionHandler) remove(w http.ResponseWriter, r *http.Request) {
+	h.member(w, r, h.CollectionService.RemoveMovie)
+}
+
+// member parses the collection and movie ids from the URL, calls change
+// with them and responds with the collection.
+func (h *CollectionHandler) member(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, id, movieID int64) error) {
+	// Parse the id and movieId params from the URL.
+	id, err := collectionID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+	movieID, err := memberID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call change to add or remove the movie in the database.
+	if err := change(r.Context(), id, movieID); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetCollection to get the collection with its new movie count.
+	if collection, err := h.CollectionService.GetCollection(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, collection)
+	}
+}
+
+// collectionID parses the id param from the URL and converts it into an
+// int64. A malformed id is reported as a missing collection.
+func collectionID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return 0, service.ErrCollectionNotFound
+	}
+
+	return id, nil
+}
+
+// decodeCollection reads a JSON collection from the request body.
+func decodeCollection(r *http.Request) (*service.Collection, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	defer r.Body.Close()
+	if err != nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "unreadable request body", Err: err}
+	}
+
+	var collection *service.Collection
+	if err := json.Unmarshal(body, &collection); err != nil || collection == nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "request body must be a JSON collection object", Err: err}
+	}
+
+	return collection, nil
+}
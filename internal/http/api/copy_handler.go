@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"../../render"
+	"../../service"
+	"github.com/go-chi/chi"
+)
+
+// CopyHandler ...
+type CopyHandler struct {
+	CopyService service.CopyService
+}
+
+// Routes creates a REST router for the copy handler.
+func (h *CopyHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Load middleware specific to this router.
+	// r.Use()
+
+	r.Get("/", h.index)
+
+	return r
+}
+
+// Index responds to a request for the copies in the library, optionally
+// only those with a barcode.
+func (h *CopyHandler) index(w http.ResponseWriter, r *http.Request) {
+	// Call GetInventory to get the copies from the database.
+	if inventory, err := h.CopyService.GetInventory(r.Context(), r.URL.Query().Get("barcode")); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, inventory)
+	}
+}
+
+// Copies responds to a request for the copies of a movie.
+func (h *MovieHandler) copies(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetCopies to get the copies from the database.
+	if copies, err := h.CopyService.GetCopies(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, copies)
+	}
+}
+
+// CreateCopy responds to a request for adding a copy of a movie.
+func (h *MovieHandler) createCopy(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Decode the request body into a temporary copy struct.
+	c, err := decodeCopy(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call CreateCopy to add the copy to the database.
+	if err := h.CopyService.CreateCopy(r.Context(), id, c); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		w.Header().Set("Location", moviePath(id)+"/copies/"+strconv.FormatInt(c.ID, 10))
+		render.JSON(w, http.StatusCreated, c)
+	}
+}
+
+// ShowCopy responds to a request for a single copy of a movie.
+func (h *MovieHandler) showCopy(w http.ResponseWriter, r *http.Request) {
+	// Parse the id and copyId params from the URL.
+	id, copyID, err := copyIDs(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetCopy to get the copy from the database.
+	if c, err := h.CopyService.GetCopy(r.Context(), id, copyID); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, c)
+	}
+}
+
+// UpdateCopy responds to a request for replacing a copy of a movie.
+func (h *MovieHandler) updateCopy(w http.ResponseWriter, r *http.Request) {
+	// Parse the id and copyId params from the URL.
+	id, copyID, err := copyIDs(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Decode the request body into a temporary copy struct.
+	c, err := decodeCopy(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call UpdateCopy to replace the copy in the database.
+	if err := h.CopyService.UpdateCopy(r.Context(), id, copyID, c); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, c)
+	}
+}
+
+// DeleteCopy responds to a request for removing a copy of a movie.
+func (h *MovieHandler) deleteCopy(w http.ResponseWriter, r *http.Request) {
+	// Parse the id and copyId params from the URL.
+	id, copyID, err := copyIDs(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call DeleteCopy to remove the copy from the database.
+	if err := h.CopyService.DeleteCopy(r.Context(), id, copyID); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, map[string]string{})
+	}
+}
+
+// copyIDs parses the id and copyId params from the URL and converts them
+// into int64s. Malformed ids are reported as a missing movie or copy.
+func copyIDs(r *http.Request) (int64, int64, error) {
+	id, err := movieID(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	copyID, err := strconv.ParseInt(chi.URLParam(r, "copyId"), 10, 64)
+	if err != nil {
+		return 0, 0, service.ErrCopyNotFound
+	}
+
+	return id, copyID, nil
+}
+
+// decodeCopy reads a JSON copy from the request body.
+func decodeCopy(r *http.Request) (*service.Copy, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	defer r.Body.Close()
+	if err != nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "unreadable request body", Err: err}
+	}
+
+	var c *service.Copy
+	if err := json.Unmarshal(body, &c); err != nil || c == nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "request body must be a JSON copy object", Err: err}
+	}
+
+	return c, nil
+}
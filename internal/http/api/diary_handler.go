@@ -0,0 +1,49 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"../../render"
+	"../../service"
+	"github.com/go-chi/chi"
+)
+
+// DiaryHandler ...
+type DiaryHandler struct {
+	ViewingService service.ViewingService
+}
+
+// Routes creates a REST router for the diary handler.
+func (h *DiaryHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Load middleware specific to this router.
+	// r.Use()
+
+	r.Get("/", h.index)
+
+	return r
+}
+
+// Index responds to a request for the viewings logged in a year.
+func (h *DiaryHandler) index(w http.ResponseWriter, r *http.Request) {
+	// Parse the year query parameter, which defaults to this year.
+	year, err := service.ParseYear(r.URL.Query().Get("year"))
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetDiary to get the viewings from the database.
+	if diary, err := h.ViewingService.GetDiary(r.Context(), year); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSONMeta(w, http.StatusOK, diary, map[string]int{"year": year})
+	}
+}
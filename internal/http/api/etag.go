@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"../../service"
+)
+
+// etag returns the entity tag of the current version of a movie. Reviews
+// and tags change a movie without changing its version, so the entity tag
+// includes a hash of the rating and tags once the movie has either. Both
+// come from the library of the movie alone.
+func etag(movie *service.Movie) string {
+	tag := strconv.FormatInt(movie.Version, 10)
+	if movie.RatingCount > 0 || len(movie.Tags) > 0 {
+		h := fnv.New32a()
+		fmt.Fprint(h, movie.RatingCount, movie.AverageRating, movie.Tags)
+		tag += "-" + strconv.FormatUint(uint64(h.Sum32()), 36)
+	}
+
+	return `"` + tag + `"`
+}
+
+// matchETag reports whether the If-Match or If-None-Match header value
+// matches tag. Weak tags only match if weak is set, as If-Match requires
+// the strong comparison.
+func matchETag(header, tag string, weak bool) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if weak {
+			t = strings.TrimPrefix(t, "W/")
+		}
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified reports whether the If-None-Match header of the request
+// matches the current version of the movie.
+func notModified(r *http.Request, movie *service.Movie) bool {
+	header := r.Header.Get("If-None-Match")
+	return header != "" && matchETag(header, etag(movie), true)
+}
+
+// ifMatch returns the version of the movie a write must be based on. It
+// is 0, meaning any version, if the request has no If-Match header, and
+// the current version if the header matches it. A header that does not
+// match fails with service.ErrMovieChanged.
+func ifMatch(r *http.Request, movie *service.Movie) (int64, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, nil
+	} else if !matchETag(header, etag(movie), false) {
+		return 0, service.ErrMovieChanged
+	}
+
+	return movie.Version, nil
+}
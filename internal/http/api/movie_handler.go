@@ -2,21 +2,34 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
 	"log"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"../../patch"
 	"../../render"
 	"../../service"
-	"../../sqlite"
 	"github.com/go-chi/chi"
 )
 
+// moviesPath is where the movie handler routes are mounted. It is used to
+// link to movies from other responses.
+const moviesPath = "/api/v1/movies"
+
 // MovieHandler ...
 type MovieHandler struct {
-	MovieService *sqlite.MovieService
+	MovieService     service.MovieService
+	MetadataService  service.MetadataService
+	ReviewService    service.ReviewService
+	ViewingService   service.ViewingService
+	WatchlistService service.WatchlistService
+	CopyService      service.CopyService
 }
 
 // Routes creates a REST router for the movie handler.
@@ -27,247 +40,688 @@ func (h *MovieHandler) Routes() chi.Router {
 	// r.Use()
 
 	r.Get("/", h.index)
+	r.Get("/search", h.search)
 	r.Post("/", h.create)
 	r.Get("/{id}", h.show)
 	r.Put("/{id}", h.update)
+	r.Patch("/{id}", h.patch)
 	r.Delete("/{id}", h.delete)
+	r.Post("/{id}/enrich", h.enrich)
+	r.Post("/{id}/restore", h.restore)
+	r.Get("/{id}/history", h.history)
+	r.Post("/{id}/history/{version}/revert", h.revert)
+	r.Get("/{id}/review", h.showReview)
+	r.Put("/{id}/review", h.putReview)
+	r.Delete("/{id}/review", h.deleteReview)
+	r.Get("/{id}/viewings", h.viewings)
+	r.Post("/{id}/viewings", h.createViewing)
+	r.Get("/{id}/watchlist", h.showWatchlistEntry)
+	r.Put("/{id}/watchlist", h.putWatchlistEntry)
+	r.Delete("/{id}/watchlist", h.deleteWatchlistEntry)
+	r.Post("/{id}/watched", h.watched)
+	r.Get("/{id}/copies", h.copies)
+	r.Post("/{id}/copies", h.createCopy)
+	r.Get("/{id}/copies/{copyId}", h.showCopy)
+	r.Put("/{id}/copies/{copyId}", h.updateCopy)
+	r.Delete("/{id}/copies/{copyId}", h.deleteCopy)
+	r.Put("/by-imdb/{imdbId}", h.upsert)
 
 	return r
 }
 
+// pageMeta is the pagination metadata rendered with a list of movies.
+type pageMeta struct {
+	Limit      int    `json:"limit"`
+	Sort       string `json:"sort"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
 // Index responds to a request for a list of movies.
 func (h *MovieHandler) index(w http.ResponseWriter, r *http.Request) {
-	// Call GetMovies to retrieve all movies from the database.
-	if movies, err := h.MovieService.GetMovies(); err != nil {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusInternalServerError,
-			map[string]string{
-				"error":   "Internal Server Error",
-				"message": err.Error(),
-			})
+	listMovies(w, r, h.MovieService, false)
+}
+
+// listMovies responds to a request for a page of the movies in the
+// library, or in the trash if trashed is set.
+func listMovies(w http.ResponseWriter, r *http.Request, movieService service.MovieService, trashed bool) {
+	// Parse the pagination, sorting and filtering query parameters.
+	filter, err := service.ParseMovieFilter(r.URL.Query())
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
+		return
+	}
+	filter.Trashed = trashed
+
+	// Call GetMovies to retrieve a page of movies from the database.
+	movies, next, err := movieService.GetMovies(r.Context(), filter)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	meta := pageMeta{Limit: filter.Limit, Sort: filter.Sort()}
+
+	// If there is another page, link to it in the headers and metadata.
+	if next != nil {
+		meta.NextCursor = next.String()
+		u := *r.URL
+		u.RawQuery = filter.Next(next).Query().Encode()
+		w.Header().Set("Link", "<"+u.String()+`>; rel="next"`)
+	}
+
+	// If the movies slice does not return nil. Respond with the movies,
+	// otherwise respond with an empty slice.
+	if *movies != nil {
+		// Render a JSON response and set status code.
+		render.JSONMeta(w, http.StatusOK, movies, meta)
 	} else {
-		// If the movies slice does not return nil. Respond with the movies,
-		// otherwise respond with an empty slice.
-		if *movies != nil {
-			// Render a JSON response and set status code.
-			render.JSON(w, http.StatusOK, movies)
-		} else {
-			// Render a JSON response and set status code.
-			render.JSON(w, http.StatusOK, []string{})
-		}
+		// Render a JSON response and set status code.
+		render.JSONMeta(w, http.StatusOK, []string{}, meta)
 	}
 }
 
-// Create responds to a request for adding a movie.
-func (h *MovieHandler) create(w http.ResponseWriter, r *http.Request) {
-	// Read the request body (limited to 1048576 bytes).
-	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
-	defer r.Body.Close()
+// Search responds to a request for the movies matching a search query.
+func (h *MovieHandler) search(w http.ResponseWriter, r *http.Request) {
+	// Parse the search query and result limit from the URL.
+	query := r.URL.Query().Get("q")
+	limit, err := service.ParseLimit(r.URL.Query().Get("limit"))
+	if err == nil && query == "" {
+		err = service.Errorf(service.EBadRequest, "missing search query q")
+	}
 	if err != nil {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusUnprocessableEntity,
-			map[string]string{
-				"error":   "Unprocessable Entity",
-				"message": err.Error(),
-			})
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
-	// Create a temporary movie struct to unmarshal the request body into.
-	var movie *service.Movie
-	err = json.Unmarshal(body, &movie)
-	if err != nil {
+	// Call SearchMovies to find the matching movies in the database.
+	if results, err := h.MovieService.SearchMovies(r.Context(), query, limit); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
 		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusUnprocessableEntity,
-			map[string]string{
-				"error":   "Unprocessable Entity",
-				"message": err.Error(),
-			})
+		render.JSON(w, http.StatusOK, results)
+	}
+}
+
+// Create responds to a request for adding a movie.
+func (h *MovieHandler) create(w http.ResponseWriter, r *http.Request) {
+	// Decode the request body into a temporary movie struct.
+	movie, err := decodeMovie(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call the CreateMovie to add the new movie to the database.
-	id, err := h.MovieService.CreateMovie(movie)
+	id, err := h.MovieService.CreateMovie(r.Context(), movie)
 	if err != nil {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusInternalServerError,
-			map[string]string{
-				"error":   "Internal Server Error",
-				"message": err.Error(),
-			})
+		// Render an error response and set status code.
+		h.movieError(w, r, err, movie.ImdbID)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if movie, err := h.MovieService.GetMovie(id); err != nil {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusNotFound,
-			map[string]string{
-				"error":   "Not Found",
-				"message": err.Error(),
-			})
+	if movie, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 	} else {
 		// Render a JSON response and set status code.
+		w.Header().Set("ETag", etag(movie))
 		render.JSON(w, http.StatusCreated, movie)
 	}
 }
 
 // Show responds to a request for a single movie.
 func (h *MovieHandler) show(w http.ResponseWriter, r *http.Request) {
-	// Parse the id param from the URL and convert it into an int64.
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	// Parse the id param from the URL.
+	id, err := movieID(r)
 	if err != nil {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusNotFound,
-			map[string]string{
-				"error":   "Not Found",
-				"message": err.Error(),
-			})
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if movie, err := h.MovieService.GetMovie(id); err != nil {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusNotFound,
-			map[string]string{
-				"error":   "Not Found",
-				"message": err.Error(),
-			})
+	movie, err := h.MovieService.GetMovie(r.Context(), id)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
-	} else {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusOK, movie)
+		return
+	}
+
+	// Skip the body if the client already has the current version.
+	w.Header().Set("ETag", etag(movie))
+	if notModified(r, movie) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
+
+	// Render a JSON response and set status code.
+	render.JSON(w, http.StatusOK, movie)
 }
 
 // Update responds to a request for updating a movie.
 func (h *MovieHandler) update(w http.ResponseWriter, r *http.Request) {
-	// Parse the id param from the URL and convert it into an int64.
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	// Parse the id param from the URL.
+	id, err := movieID(r)
 	if err != nil {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusNotFound,
-			map[string]string{
-				"error":   "Not Found",
-				"message": err.Error(),
-			})
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if _, err := h.MovieService.GetMovie(id); err != nil {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusNotFound,
-			map[string]string{
-				"error":   "Not Found",
-				"message": err.Error(),
-			})
+	current, err := h.MovieService.GetMovie(r.Context(), id)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
-	// Read the request body (limited to 1048576 bytes).
-	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
-	defer r.Body.Close()
+	// Check the If-Match header against the current version.
+	version, err := ifMatch(r, current)
 	if err != nil {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusUnprocessableEntity,
-			map[string]string{
-				"error":   "Unprocessable Entity",
-				"message": err.Error(),
-			})
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
-	// Create a temporary movie struct to unmarshal the request body into.
-	var movie *service.Movie
-	err = json.Unmarshal(body, &movie)
+	// Decode the request body into a temporary movie struct.
+	movie, err := decodeMovie(r)
 	if err != nil {
-		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusUnprocessableEntity,
-			map[string]string{
-				"error":   "Unprocessable Entity",
-				"message": err.Error(),
-			})
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call UpdateMovie to update the movie in the database.
-	err = h.MovieService.UpdateMovie(id, movie)
+	movie.Version = version
+	err = h.MovieService.UpdateMovie(r.Context(), id, movie)
 	if err != nil {
+		// Render an error response and set status code.
+		h.movieError(w, r, err, movie.ImdbID)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetMovie to get the movie from the database.
+	if movie, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
 		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusInternalServerError,
-			map[string]string{
-				"error":   "Internal Server Error",
-				"message": err.Error(),
-			})
+		w.Header().Set("ETag", etag(movie))
+		render.JSON(w, http.StatusCreated, movie)
+	}
+}
+
+// Patch responds to a request for updating some of the fields of a movie.
+// The body is a JSON Merge Patch, or a JSON Patch if the content type is
+// application/json-patch+json.
+func (h *MovieHandler) patch(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if movie, err := h.MovieService.GetMovie(id); err != nil {
+	movie, err := h.MovieService.GetMovie(r.Context(), id)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Check the If-Match header against the current version.
+	version, err := ifMatch(r, movie)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Apply the patch to the movie to find the fields to update.
+	patched, err := patchMovie(r, movie)
+	if err != nil {
+		// Advertise the supported formats if the format was rejected.
+		if service.ErrorCode(err) == service.EUnsupported {
+			w.Header().Set("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType)
+		}
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call PatchMovie to update the changed fields in the database.
+	update := service.NewMovieUpdate(movie, patched)
+	update.Version = version
+	err = h.MovieService.PatchMovie(r.Context(), id, update)
+	if err != nil {
+		// Render an error response and set status code.
+		h.movieError(w, r, err, patched.ImdbID)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetMovie to get the movie from the database.
+	if movie, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
 		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusNotFound,
-			map[string]string{
-				"error":   "Not Found",
-				"message": err.Error(),
-			})
+		w.Header().Set("ETag", etag(movie))
+		render.JSON(w, http.StatusOK, movie)
+	}
+}
+
+// Upsert responds to a request for creating or updating the movie with
+// the IMDb id in the URL. Repeating the request gives the same result, so
+// it is safe for sync scripts to retry.
+func (h *MovieHandler) upsert(w http.ResponseWriter, r *http.Request) {
+	// Decode the request body into a temporary movie struct.
+	movie, err := decodeMovie(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// The IMDb id in the URL identifies the movie, so the body can only
+	// repeat it, and the metadata can only come from enriching the movie.
+	imdbID := chi.URLParam(r, "imdbId")
+	if err := checkUpsert(movie, imdbID); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+	movie.ImdbID = imdbID
+
+	// Check the If-Match header against the current version. The header
+	// can only match a movie that already exists.
+	if r.Header.Get("If-Match") != "" {
+		existing, err := h.MovieService.GetMovieByImdbID(r.Context(), imdbID)
+		if service.ErrorCode(err) == service.ENotFound {
+			err = service.ErrMovieChanged
+		}
+		if err == nil {
+			movie.Version, err = ifMatch(r, existing)
+		}
+		if err != nil {
+			// Render an error response and set status code.
+			render.Error(w, r, err)
+			log.Println("Error:", err)
+			return
+		}
+	}
+
+	// Call UpsertMovie to create or update the movie in the database.
+	id, created, err := h.MovieService.UpsertMovie(r.Context(), movie)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		w.Header().Set("Location", moviePath(id))
+	}
+
+	// Call GetMovie to get the movie from the database.
+	if movie, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 	} else {
 		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusCreated, movie)
+		w.Header().Set("ETag", etag(movie))
+		render.JSON(w, status, movie)
 	}
 }
 
 // Delete responds to a request for removing a movie.
 func (h *MovieHandler) delete(w http.ResponseWriter, r *http.Request) {
-	// Parse the id param from the URL and convert it into an int64.
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetMovie to get the movie from the database.
+	current, err := h.MovieService.GetMovie(r.Context(), id)
 	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Check the If-Match header against the current version.
+	version, err := ifMatch(r, current)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call DeleteMovie to remove the movie from the database.
+	if err = h.MovieService.DeleteMovie(r.Context(), id, version); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
 		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusNotFound,
-			map[string]string{
-				"error":   "Not Found",
-				"message": err.Error(),
-			})
+		render.JSON(w, http.StatusOK, map[string]string{})
+	}
+}
+
+// Restore responds to a request for moving a movie out of the trash.
+func (h *MovieHandler) restore(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call RestoreMovie to move the movie back into the library.
+	if err := h.MovieService.RestoreMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if _, err := h.MovieService.GetMovie(id); err != nil {
+	if movie, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
 		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusNotFound,
-			map[string]string{
-				"error":   "Not Found",
-				"message": err.Error(),
-			})
+		w.Header().Set("ETag", etag(movie))
+		render.JSON(w, http.StatusOK, movie)
+	}
+}
+
+// Enrich responds to a request for fetching a movie's external metadata.
+func (h *MovieHandler) enrich(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
-	// Call DeleteMovie to remove the movie from the database.
-	if err = h.MovieService.DeleteMovie(id); err != nil {
+	// Call GetMovie to get the movie from the database.
+	movie, err := h.MovieService.GetMovie(r.Context(), id)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetMetadata to fetch the movie's metadata by its IMDb id.
+	md, err := h.MetadataService.GetMetadata(r.Context(), movie.ImdbID)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call UpdateMetadata to store the metadata in the database.
+	err = h.MovieService.UpdateMetadata(r.Context(), id, md)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetMovie to get the movie from the database.
+	if movie, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
 		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusInternalServerError,
-			map[string]string{
-				"error":   "Internal Server Error",
-				"message": err.Error(),
-			})
+		w.Header().Set("ETag", etag(movie))
+		render.JSON(w, http.StatusOK, movie)
+	}
+}
+
+// history lists the recorded changes to a movie, most recent first.
+func (h *MovieHandler) history(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetHistory to get the history of the movie from the database.
+	if history, err := h.MovieService.GetHistory(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
 		log.Println("Error:", err)
 	} else {
 		// Render a JSON response and set status code.
-		render.JSON(w, http.StatusOK, map[string]string{})
+		render.JSON(w, http.StatusOK, history)
+	}
+}
+
+// revert sets a movie back to how it was at a version in its history. The
+// revert is recorded as a new version.
+func (h *MovieHandler) revert(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
 	}
+
+	// Parse the version param from the URL.
+	version, err := strconv.ParseInt(chi.URLParam(r, "version"), 10, 64)
+	if err != nil {
+		err = service.ErrVersionNotFound
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call RevertMovie to set the movie back to the version.
+	if err := h.MovieService.RevertMovie(r.Context(), id, version); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetMovie to get the movie from the database.
+	if movie, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		w.Header().Set("ETag", etag(movie))
+		render.JSON(w, http.StatusOK, movie)
+	}
+}
+
+// movieID parses the id param from the URL and converts it into an int64.
+// A malformed id is reported as a missing movie.
+func movieID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return 0, service.Errorf(service.ENotFound, "movie not found")
+	}
+
+	return id, nil
+}
+
+// decodeMovie reads the request body (limited to 1048576 bytes) and
+// unmarshals it into a movie.
+func decodeMovie(r *http.Request) (*service.Movie, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	defer r.Body.Close()
+	if err != nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "unreadable request body", Err: err}
+	}
+
+	var movie *service.Movie
+	if err := json.Unmarshal(body, &movie); err != nil || movie == nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "request body must be a JSON movie object", Err: err}
+	}
+
+	return movie, nil
+}
+
+// moviePath returns the URL path of the movie with the given id.
+func moviePath(id int64) string {
+	return moviesPath + "/" + strconv.FormatInt(id, 10)
+}
+
+// movieError renders err for a movie with the given IMDb id. Conflicts
+// link to the existing movie with that IMDb id in the problem and in a
+// Link header.
+func (h *MovieHandler) movieError(w http.ResponseWriter, r *http.Request, err error, imdbID string) {
+	p := render.NewProblem(r, err)
+	if service.ErrorCode(err) == service.EConflict {
+		if existing, err := h.MovieService.GetMovieByImdbID(r.Context(), imdbID); err == nil {
+			p.Existing = moviePath(existing.ID)
+			w.Header().Set("Link", "<"+p.Existing+`>; rel="duplicate"`)
+		}
+	}
+
+	render.ProblemJSON(w, p)
+}
+
+// patchMovie applies the patch in the request body to the movie and
+// returns the patched copy. Fields that are maintained by the server
+// cannot be patched.
+func patchMovie(r *http.Request, movie *service.Movie) (*service.Movie, error) {
+	apply := patch.Merge
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case patch.MergePatchType, "application/json", "":
+	case patch.JSONPatchType:
+		apply = patch.Apply
+	default:
+		return nil, service.Errorf(service.EUnsupported, "unsupported patch format %q", mediaType)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	defer r.Body.Close()
+	if err != nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "unreadable request body", Err: err}
+	}
+
+	doc, err := json.Marshal(movie)
+	if err != nil {
+		return nil, err
+	}
+
+	// Patch errors only describe the patch, so they are safe to return.
+	doc, err = apply(doc, body)
+	if errors.Is(err, patch.ErrInvalid) {
+		return nil, service.Errorf(service.EBadRequest, "%v", err)
+	} else if errors.Is(err, patch.ErrTestFailed) {
+		return nil, service.Errorf(service.EConflict, "%v", err)
+	} else if err != nil {
+		return nil, service.Errorf(service.EInvalid, "the patch cannot be applied: %v", err)
+	}
+
+	var patched service.Movie
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return nil, &service.Error{Code: service.EInvalid, Message: "the patched movie is not a movie", Err: err}
+	}
+
+	v := service.Validation{}
+	v.Check(patched.ID == movie.ID, "id", "is read-only")
+	v.Check(patched.CreatedAt.Equal(movie.CreatedAt), "createdAt", "is read-only")
+	v.Check(patched.UpdatedAt.Equal(movie.UpdatedAt), "updatedAt", "is read-only")
+	v.Check(equalTimes(patched.EnrichedAt, movie.EnrichedAt), "enrichedAt", "is read-only")
+	v.Check(equalTimes(patched.DeletedAt, movie.DeletedAt), "deletedAt", "is read-only")
+	v.Check(patched.AverageRating == movie.AverageRating, "averageRating", "is read-only")
+	v.Check(patched.RatingCount == movie.RatingCount, "ratingCount", "is read-only")
+	v.Check(strings.Join(patched.Tags, "\x00") == strings.Join(movie.Tags, "\x00"), "tags", "is read-only")
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return &patched, nil
+}
+
+// checkUpsert returns a validation error if the body of an upsert sets
+// more than the title and the IMDb id from the URL.
+func checkUpsert(movie *service.Movie, imdbID string) error {
+	const enriched = "can only be set by enriching the movie"
+
+	v := service.Validation{}
+	v.Check(movie.ImdbID == "" || movie.ImdbID == imdbID, "imdbId", "must match the IMDb id in the URL")
+	v.Check(movie.Year == 0, "year", enriched)
+	v.Check(movie.Runtime == 0, "runtime", enriched)
+	v.Check(len(movie.Genres) == 0, "genres", enriched)
+	v.Check(movie.Director == "", "director", enriched)
+	v.Check(len(movie.Cast) == 0, "cast", enriched)
+	v.Check(movie.Plot == "", "plot", enriched)
+	v.Check(movie.PosterURL == "", "posterUrl", enriched)
+	v.Check(movie.EnrichedAt == nil, "enrichedAt", "is read-only")
+
+	return v.Err()
+}
+
+// equalTimes reports whether a and b are both nil or the same time.
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
 }
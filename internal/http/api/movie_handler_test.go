@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"../../memory"
+	"../../service"
+)
+
+func TestMain(m *testing.M) {
+	// The handlers log every error they render.
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+// request is a request to the movie handler and the response expected.
+type request struct {
+	method  string
+	path    string
+	body    string
+	header  map[string]string
+	status  int
+	want    map[string]interface{} // Members expected in the data or problem.
+	headers map[string]string      // Headers expected in the response.
+}
+
+// run sends the requests in order to a movie handler backed by an empty
+// in-memory store, checking each response.
+func run(t *testing.T, requests []request) {
+	t.Helper()
+
+	h := &MovieHandler{MovieService: &memory.MovieService{}}
+	routes := h.Routes()
+
+	for _, req := range requests {
+		r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+		r.Header.Set("Content-Type", "application/json")
+		for k, v := range req.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		routes.ServeHTTP(w, r)
+
+		name := req.method + " " + req.path
+		if w.Code != req.status {
+			t.Fatalf("%s status = %d, want %d: %s", name, w.Code, req.status, w.Body)
+		}
+		for k, v := range req.headers {
+			if got := w.Header().Get(k); got != v {
+				t.Errorf("%s %s header = %q, want %q", name, k, got, v)
+			}
+		}
+		if len(req.want) == 0 {
+			continue
+		}
+
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		var problem map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s body is not JSON: %v", name, err)
+		}
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		got := body.Data
+		if got == nil {
+			got = problem
+		}
+		for k, v := range req.want {
+			if got[k] != v {
+				t.Errorf("%s %s = %v, want %v", name, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestMovieHandlerCreate(t *testing.T) {
+	run(t, []request{
+		{
+			method: "POST", path: "/", body: `{"title":"Heat","imdbId":"tt0113277"}`,
+			status:  http.StatusCreated,
+			want:    map[string]interface{}{"id": 1.0, "title": "Heat"},
+			headers: map[string]string{"ETag": `"1"`},
+		},
+		{
+			method: "POST", path: "/", body: `{"title":"Heat Again","imdbId":"tt0113277"}`,
+			status:  http.StatusConflict,
+			want:    map[string]interface{}{"code": service.EConflict, "existing": "/api/v1/movies/1"},
+			headers: map[string]string{"Link": `</api/v1/movies/1>; rel="duplicate"`},
+		},
+		{
+			method: "POST", path: "/", body: `{"title":"","imdbId":"nope"}`,
+			status: http.StatusUnprocessableEntity,
+			want:   map[string]interface{}{"code": service.EInvalid},
+		},
+		{method: "POST", path: "/", body: `[]`, status: http.StatusBadRequest},
+		{method: "GET", path: "/1", status: http.StatusOK, want: map[string]interface{}{"imdbId": "tt0113277"}},
+		{method: "GET", path: "/2", status: http.StatusNotFound},
+		{method: "GET", path: "/abc", status: http.StatusNotFound},
+	})
+}
+
+func TestMovieHandlerVersions(t *testing.T) {
+	run(t, []request{
+		{method: "POST", path: "/", body: `{"title":"Heat","imdbId":"tt0113277"}`, status: http.StatusCreated},
+		{method: "GET", path: "/1", header: map[string]string{"If-None-Match": `"1"`}, status: http.StatusNotModified},
+		{
+			method: "PUT", path: "/1", body: `{"title":"Heat (1995)","imdbId":"tt0113277"}`,
+			header:  map[string]string{"If-Match": `"1"`},
+			status:  http.StatusCreated,
+			want:    map[string]interface{}{"title": "Heat (1995)"},
+			headers: map[string]string{"ETag": `"2"`},
+		},
+		{
+			method: "PUT", path: "/1", body: `{"title":"Stale","imdbId":"tt0113277"}`,
+			header: map[string]string{"If-Match": `"1"`},
+			status: http.StatusPreconditionFailed,
+		},
+		{
+			method: "PATCH", path: "/1", body: `{"director":"Michael Mann"}`,
+			header: map[string]string{"If-Match": `"2"`},
+			status: http.StatusOK,
+			want:   map[string]interface{}{"title": "Heat (1995)", "director": "Michael Mann"},
+		},
+		{
+			method: "PATCH", path: "/1", body: `{"id":7}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			method: "PATCH", path: "/1", body: `[{"op":"test","path":"/title","value":"Heat"}]`,
+			header: map[string]string{"Content-Type": "application/json-patch+json"},
+			status: http.StatusConflict,
+		},
+		{method: "GET", path: "/1/history", status: http.StatusOK},
+		{method: "DELETE", path: "/1", header: map[string]string{"If-Match": `"1"`}, status: http.StatusPreconditionFailed},
+	})
+}
+
+func TestMovieHandlerUpsert(t *testing.T) {
+	run(t, []request{
+		{
+			method: "PUT", path: "/by-imdb/tt0113277", body: `{"title":"Heat"}`,
+			header: map[string]string{"If-Match": "*"},
+			status: http.StatusPreconditionFailed,
+		},
+		{
+			method: "PUT", path: "/by-imdb/tt0113277", body: `{"title":"Heat"}`,
+			status:  http.StatusCreated,
+			headers: map[string]string{"Location": "/api/v1/movies/1"},
+		},
+		{
+			method: "PUT", path: "/by-imdb/tt0113277", body: `{"title":"Heat (1995)"}`,
+			header: map[string]string{"If-Match": `"1"`},
+			status: http.StatusOK,
+			want:   map[string]interface{}{"id": 1.0, "title": "Heat (1995)"},
+		},
+		{
+			method: "PUT", path: "/by-imdb/tt0113277", body: `{"title":"Heat","imdbId":"tt0000001"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			method: "PUT", path: "/by-imdb/tt0113277", body: `{"title":"Heat","director":"Michael Mann"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+	})
+}
+
+func TestMovieHandlerTrash(t *testing.T) {
+	run(t, []request{
+		{method: "POST", path: "/", body: `{"title":"Heat","imdbId":"tt0113277"}`, status: http.StatusCreated},
+		{method: "DELETE", path: "/1", status: http.StatusOK},
+		{method: "DELETE", path: "/1", status: http.StatusNotFound},
+		{method: "GET", path: "/?limit=10", status: http.StatusOK},
+		{method: "GET", path: "/1", status: http.StatusOK, want: map[string]interface{}{"title": "Heat"}},
+		{method: "POST", path: "/1/restore", status: http.StatusOK},
+		{method: "POST", path: "/2/restore", status: http.StatusNotFound},
+	})
+}
+
+func TestMovieHandlerIndex(t *testing.T) {
+	requests := []request{}
+	for _, m := range []string{`{"title":"Alien","imdbId":"tt0078748"}`, `{"title":"Heat","imdbId":"tt0113277"}`, `{"title":"Ran","imdbId":"tt0089881"}`} {
+		requests = append(requests, request{method: "POST", path: "/", body: m, status: http.StatusCreated})
+	}
+	requests = append(requests,
+		request{method: "GET", path: "/?limit=2&sort=-title", status: http.StatusOK},
+		request{method: "GET", path: "/?limit=0", status: http.StatusBadRequest},
+		request{method: "GET", path: "/?sort=rating", status: http.StatusBadRequest},
+		request{method: "GET", path: "/search?q=hea", status: http.StatusOK},
+		request{method: "GET", path: "/search", status: http.StatusBadRequest},
+	)
+	run(t, requests)
+}
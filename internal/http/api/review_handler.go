@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"../../render"
+	"../../service"
+)
+
+// ShowReview responds to a request for the review of a movie.
+func (h *MovieHandler) showReview(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetReview to get the review from the database.
+	if review, err := h.ReviewService.GetReview(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, review)
+	}
+}
+
+// PutReview responds to a request for adding or replacing the review of
+// a movie.
+func (h *MovieHandler) putReview(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Decode the request body into a temporary review struct.
+	review, err := decodeReview(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call PutReview to add the review to the database.
+	created, err := h.ReviewService.PutReview(r.Context(), id, review)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		w.Header().Set("Location", moviePath(id)+"/review")
+	}
+
+	// Render a JSON response and set status code.
+	render.JSON(w, status, review)
+}
+
+// DeleteReview responds to a request for removing the review of a movie.
+func (h *MovieHandler) deleteReview(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call DeleteReview to remove the review from the database.
+	if err := h.ReviewService.DeleteReview(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, map[string]string{})
+	}
+}
+
+// decodeReview reads a JSON review from the request body.
+func decodeReview(r *http.Request) (*service.Review, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	defer r.Body.Close()
+	if err != nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "unreadable request body", Err: err}
+	}
+
+	var review *service.Review
+	if err := json.Unmarshal(body, &review); err != nil || review == nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "request body must be a JSON review object", Err: err}
+	}
+
+	return review, nil
+}
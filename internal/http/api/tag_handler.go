@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"../../render"
+	"../../service"
+	"github.com/go-chi/chi"
+)
+
+// tagsPath is where the tag handler routes are mounted.
+const tagsPath = "/api/v1/tags"
+
+// TagHandler ...
+type TagHandler struct {
+	TagService service.TagService
+}
+
+// Routes creates a REST router for the tag handler.
+func (h *TagHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Load middleware specific to this router.
+	// r.Use()
+
+	r.Get("/", h.index)
+	r.Post("/", h.create)
+	r.Get("/{id}", h.show)
+	r.Put("/{id}", h.update)
+	r.Delete("/{id}", h.delete)
+	r.Put("/{id}/movies/{movieId}", h.tag)
+	r.Delete("/{id}/movies/{movieId}", h.untag)
+
+	return r
+}
+
+// Index responds to a request for a list of tags.
+func (h *TagHandler) index(w http.ResponseWriter, r *http.Request) {
+	// Call GetTags to retrieve all tags from the database.
+	if tags, err := h.TagService.GetTags(r.Context()); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, tags)
+	}
+}
+
+// Create responds to a request for adding a tag.
+func (h *TagHandler) create(w http.ResponseWriter, r *http.Request) {
+	// Decode the request body into a temporary tag struct.
+	tag, err := decodeTag(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call CreateTag to add the new tag to the database.
+	if err := h.TagService.CreateTag(r.Context(), tag); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		w.Header().Set("Location", tagsPath+"/"+strconv.FormatInt(tag.ID, 10))
+		render.JSON(w, http.StatusCreated, tag)
+	}
+}
+
+// Show responds to a request for a single tag.
+func (h *TagHandler) show(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := tagID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetTag to get the tag from the database.
+	if tag, err := h.TagService.GetTag(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, tag)
+	}
+}
+
+// Update responds to a request for renaming a tag.
+func (h *TagHandler) update(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := tagID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Decode the request body into a temporary tag struct.
+	tag, err := decodeTag(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call UpdateTag to rename the tag in the database.
+	if err := h.TagService.UpdateTag(r.Context(), id, tag); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, tag)
+	}
+}
+
+// Delete responds to a request for removing a tag.
+func (h *TagHandler) delete(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := tagID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call DeleteTag to remove the tag from the database.
+	if err := h.TagService.DeleteTag(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, map[string]string{})
+	}
+}
+
+// Tag responds to a request for giving a movie a tag.
+func (h *TagHandler) tag(w http.ResponseWriter, r *http.Request) {
+	h.member(w, r, h.TagService.TagMovie)
+}
+
+// Untag responds to a request for removing a tag from a movie.
+func (h *TagHandler) untag(w http.ResponseWriter, r *http.Request) {
+	h.member(w, r, h.TagService.UntagMovie)
+}
+
+// member parses the tag and movie ids from the URL, calls change with
+// them and responds with the tag.
+func (h *TagHandler) member(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, id, movieID int64) error) {
+	// Parse the id and movieId params from the URL.
+	id, err := tagID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+	movieID, err := memberID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call change to tag or untag the movie in the database.
+	if err := change(r.Context(), id, movieID); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetTag to get the tag with its new movie count.
+	if tag, err := h.TagService.GetTag(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, tag)
+	}
+}
+
+// tagID parses the id param from the URL and converts it into an int64.
+// A malformed id is reported as a missing tag.
+func tagID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return 0, service.ErrTagNotFound
+	}
+
+	return id, nil
+}
+
+// memberID parses the movieId param from the URL of a tag or collection
+// member and converts it into an int64. A malformed id is reported as a
+// missing movie.
+func memberID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "movieId"), 10, 64)
+	if err != nil {
+		return 0, service.Errorf(service.ENotFound, "movie not found")
+	}
+
+	return id, nil
+}
+
+// decodeTag reads a JSON tag from the request body.
+func decodeTag(r *http.Request) (*service.Tag, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	defer r.Body.Close()
+	if err != nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "unreadable request body", Err: err}
+	}
+
+	var tag *service.Tag
+	if err := json.Unmarshal(body, &tag); err != nil || tag == nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "request body must be a JSON tag object", Err: err}
+	}
+
+	return tag, nil
+}
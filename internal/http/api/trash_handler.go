@@ -0,0 +1,71 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"../../render"
+	"../../service"
+	"github.com/go-chi/chi"
+)
+
+// TrashHandler ...
+type TrashHandler struct {
+	MovieService service.MovieService
+}
+
+// Routes creates a REST router for the trash handler.
+func (h *TrashHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Load middleware specific to this router.
+	// r.Use()
+
+	r.Get("/", h.index)
+	r.Delete("/", h.empty)
+	r.Delete("/{id}", h.purge)
+
+	return r
+}
+
+// Index responds to a request for a list of the movies in the trash.
+func (h *TrashHandler) index(w http.ResponseWriter, r *http.Request) {
+	listMovies(w, r, h.MovieService, true)
+}
+
+// Empty responds to a request for permanently removing every movie in the
+// trash.
+func (h *TrashHandler) empty(w http.ResponseWriter, r *http.Request) {
+	// Call EmptyTrash to remove the movies from the database.
+	if n, err := h.MovieService.EmptyTrash(r.Context()); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, map[string]int64{"purged": n})
+	}
+}
+
+// Purge responds to a request for permanently removing a movie in the
+// trash.
+func (h *TrashHandler) purge(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call PurgeMovie to remove the movie from the database.
+	if err := h.MovieService.PurgeMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, map[string]string{})
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"../../render"
+	"../../service"
+)
+
+// Viewings responds to a request for the viewings of a movie.
+func (h *MovieHandler) viewings(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetViewings to get the viewings from the database.
+	if viewings, err := h.ViewingService.GetViewings(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, viewings)
+	}
+}
+
+// CreateViewing responds to a request for logging a viewing of a movie.
+func (h *MovieHandler) createViewing(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Decode the request body into a temporary viewing struct.
+	viewing, err := decodeViewing(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call CreateViewing to add the viewing to the database.
+	if err := h.ViewingService.CreateViewing(r.Context(), id, viewing); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusCreated, viewing)
+	}
+}
+
+// decodeViewing reads a JSON viewing from the request body. An empty body
+// is an empty viewing, which is logged today.
+func decodeViewing(r *http.Request) (*service.Viewing, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	defer r.Body.Close()
+	if err != nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "unreadable request body", Err: err}
+	} else if len(bytes.TrimSpace(body)) == 0 {
+		return &service.Viewing{}, nil
+	}
+
+	var viewing *service.Viewing
+	if err := json.Unmarshal(body, &viewing); err != nil || viewing == nil {
+		return nil, &service.Error{Code: service.EBadRequest, Message: "request body must be a JSON viewing object", Err: err}
+	}
+
+	return viewing, nil
+}
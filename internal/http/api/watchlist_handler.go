@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"../../render"
+	"../../service"
+	"github.com/go-chi/chi"
+)
+
+// WatchlistHandler ...
+type WatchlistHandler struct {
+	WatchlistService service.WatchlistService
+}
+
+// Routes creates a REST router for the watchlist handler.
+func (h *WatchlistHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Load middleware specific to this router.
+	// r.Use()
+
+	r.Get("/", h.index)
+	r.Post("/reorder", h.reorder)
+
+	return r
+}
+
+// Index responds to a request for the watchlist.
+func (h *WatchlistHandler) index(w http.ResponseWriter, r *http.Request) {
+	// Parse the optional status query parameter.
+	status, err := service.ParseStatus(r.URL.Query().Get("status"))
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetWatchlist to get the watchlist from the database.
+	if watchlist, err := h.WatchlistService.GetWatchlist(r.Context(), status); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, watchlist)
+	}
+}
+
+// Reorder responds to a request for moving movies to the top of the
+// watchlist.
+func (h *WatchlistHandler) reorder(w http.ResponseWriter, r *http.Request) {
+	// Decode the request body into the ids of the movies to move.
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	defer r.Body.Close()
+	if err != nil {
+		err = &service.Error{Code: service.EBadRequest, Message: "unreadable request body", Err: err}
+	}
+
+	var req struct {
+		MovieIDs []int64 `json:"movieIds"`
+	}
+	if err == nil {
+		if e := json.Unmarshal(body, &req); e != nil || req.MovieIDs == nil {
+			err = &service.Error{Code: service.EBadRequest, Message: "request body must be a JSON object with movieIds", Err: e}
+		}
+	}
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call ReorderWatchlist to save the new order in the database.
+	if watchlist, err := h.WatchlistService.ReorderWatchlist(r.Context(), req.MovieIDs); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, watchlist)
+	}
+}
+
+// ShowWatchlistEntry responds to a request for the watchlist entry of a
+// movie.
+func (h *MovieHandler) showWatchlistEntry(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetWatchlistEntry to get the entry from the database.
+	if entry, err := h.WatchlistService.GetWatchlistEntry(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, entry)
+	}
+}
+
+// PutWatchlistEntry responds to a request for adding a movie to the
+// watchlist or changing its status.
+func (h *MovieHandler) putWatchlistEntry(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Decode the request body into a temporary watchlist entry struct.
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	defer r.Body.Close()
+	if err != nil {
+		err = &service.Error{Code: service.EBadRequest, Message: "unreadable request body", Err: err}
+	}
+
+	var entry *service.WatchlistEntry
+	if err == nil {
+		if e := json.Unmarshal(body, &entry); e != nil || entry == nil {
+			err = &service.Error{Code: service.EBadRequest, Message: "request body must be a JSON watchlist entry object", Err: e}
+		}
+	}
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call PutWatchlistEntry to save the entry in the database.
+	created, err := h.WatchlistService.PutWatchlistEntry(r.Context(), id, entry)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		w.Header().Set("Location", moviePath(id)+"/watchlist")
+	}
+
+	// Render a JSON response and set status code.
+	render.JSON(w, status, entry)
+}
+
+// DeleteWatchlistEntry responds to a request for removing a movie from
+// the watchlist.
+func (h *MovieHandler) deleteWatchlistEntry(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call DeleteWatchlistEntry to remove the entry from the database.
+	if err := h.WatchlistService.DeleteWatchlistEntry(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusOK, map[string]string{})
+	}
+}
+
+// Watched responds to a request for marking a movie on the watchlist as
+// watched, which logs a viewing of it.
+func (h *MovieHandler) watched(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Decode the optional request body into a temporary viewing struct.
+	viewing, err := decodeViewing(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call MarkWatched to update the watchlist and log the viewing.
+	if err := h.WatchlistService.MarkWatched(r.Context(), id, viewing); err != nil {
+		// Render an error response and set status code.
+		render.Error(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		// Render a JSON response and set status code.
+		render.JSON(w, http.StatusCreated, viewing)
+	}
+}
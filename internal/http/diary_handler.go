@@ -0,0 +1,82 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"../render"
+	"../service"
+	"github.com/go-chi/chi"
+)
+
+// DiaryHandler ...
+type DiaryHandler struct {
+	ViewingService service.ViewingService
+}
+
+// diaryMonth is a month of the diary page along with the viewings logged
+// in it, most recent first.
+type diaryMonth struct {
+	Name    string
+	Entries service.Diary
+}
+
+// Routes creates a REST router for the diary handler.
+func (h *DiaryHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Load middleware specific to this router.
+	// r.Use()
+
+	r.Get("/", h.index)
+
+	return r
+}
+
+// Index responds to a request for the viewings logged in a year, grouped
+// by month.
+func (h *DiaryHandler) index(w http.ResponseWriter, r *http.Request) {
+	// Parse the year query parameter, which defaults to this year.
+	year, err := service.ParseYear(r.URL.Query().Get("year"))
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetDiary to get the viewings from the database.
+	diary, err := h.ViewingService.GetDiary(r.Context(), year)
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Render a HTML response and set status code.
+	render.HTML(w, http.StatusOK, "diary/index.html", struct {
+		Year   int
+		Prev   int
+		Next   int
+		Months []*diaryMonth
+		User   *service.User
+	}{year, year - 1, year + 1, groupByMonth(*diary), service.UserFromContext(r.Context())})
+}
+
+// groupByMonth splits a diary, most recent first, into its months.
+func groupByMonth(diary service.Diary) []*diaryMonth {
+	var months []*diaryMonth
+	for _, entry := range diary {
+		t, _ := time.Parse(service.DateLayout, entry.WatchedOn)
+		name := t.Format("January")
+		if len(months) == 0 || months[len(months)-1].Name != name {
+			months = append(months, &diaryMonth{Name: name})
+		}
+		month := months[len(months)-1]
+		month.Entries = append(month.Entries, entry)
+	}
+
+	return months
+}
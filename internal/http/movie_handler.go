@@ -1,19 +1,36 @@
 package http
 
 import (
+	"html/template"
 	"log"
 	"net/http"
 	"strconv"
 
 	"../render"
 	"../service"
-	"../sqlite"
 	"github.com/go-chi/chi"
 )
 
 // MovieHandler ...
 type MovieHandler struct {
-	MovieService *sqlite.MovieService
+	MovieService  service.MovieService
+	ReviewService service.ReviewService
+	CopyService   service.CopyService
+}
+
+// movieForm is the data for the new and edit movie forms. Errors holds the
+// field errors of a rejected submission, keyed by the JSON field name.
+type movieForm struct {
+	*service.Movie
+	Errors service.Validation
+}
+
+// movieShow is the data for the movie page. Review is nil if the movie
+// has not been reviewed.
+type movieShow struct {
+	*service.Movie
+	Review *service.Review
+	Copies service.Copies
 }
 
 // Routes creates a REST router for the page handler.
@@ -24,11 +41,13 @@ func (h *MovieHandler) Routes() chi.Router {
 	// r.Use()
 
 	r.Get("/", h.index)
+	r.Get("/search", h.search)
 	r.Get("/new", h.new)
 	r.Post("/", h.create)
 	r.Get("/{id}", h.show)
 	r.Get("/{id}/edit", h.edit)
 	r.Put("/{id}", h.update)
+	r.Post("/{id}/edit", h.update)
 	r.Post("/{id}", h.delete)
 
 	return r
@@ -36,30 +55,84 @@ func (h *MovieHandler) Routes() chi.Router {
 
 // Index responds to a request for a list of movies.
 func (h *MovieHandler) index(w http.ResponseWriter, r *http.Request) {
-	// Call GetMovies to retrieve all movies from the database.
-	if movies, err := h.MovieService.GetMovies(); err != nil {
+	// Parse the pagination, sorting and filtering query parameters.
+	filter, err := service.ParseMovieFilter(r.URL.Query())
+	if err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetMovies to retrieve a page of movies from the database.
+	movies, next, err := h.MovieService.GetMovies(r.Context(), filter)
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Link to the next page if there is one.
+	var nextURL string
+	if next != nil {
+		nextURL = "/movies?" + filter.Next(next).Query().Encode()
+	}
+
+	// Render a HTML response and set status code.
+	render.HTML(w, http.StatusOK, "movie/index.html", struct {
+		Movies *service.Movies
+		Filter service.MovieFilter
+		Next   string
+		User   *service.User
+	}{movies, filter, nextURL, service.UserFromContext(r.Context())})
+}
+
+// Search responds to a request for the movies matching a search query.
+func (h *MovieHandler) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+
+	// Call SearchMovies to find the matching movies in the database.
+	if results, err := h.MovieService.SearchMovies(r.Context(), query, service.MaxLimit); err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 	} else {
+		// The snippets are already escaped, with only their <mark> tags
+		// left as HTML.
+		var snippets []searchResult
+		for _, result := range *results {
+			snippets = append(snippets, searchResult{result, template.HTML(result.Snippet)})
+		}
+
 		// Render a HTML response and set status code.
-		render.HTML(w, http.StatusOK, "movie/index.html", movies)
+		render.HTML(w, http.StatusOK, "movie/search.html", struct {
+			Query   string
+			Results []searchResult
+		}{query, snippets})
 	}
 }
 
+// searchResult is a search result on the search page, with its snippet
+// marked as safe HTML.
+type searchResult struct {
+	*service.SearchResult
+	Snippet template.HTML
+}
+
 // New responds to a request for entering details for a movie.
 func (h *MovieHandler) new(w http.ResponseWriter, r *http.Request) {
 	// Render a HTML response and set status code.
-	render.HTML(w, http.StatusOK, "movie/new.html", nil)
+	render.HTML(w, http.StatusOK, "movie/new.html", movieForm{Movie: &service.Movie{}})
 }
 
 // Create responds to a request for adding a movie.
 func (h *MovieHandler) create(w http.ResponseWriter, r *http.Request) {
 	// Parse the page form values.
-	err := r.ParseForm()
+	err := parseForm(r)
 	if err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Unprocessable Entity", http.StatusUnprocessableEntity)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
@@ -71,18 +144,22 @@ func (h *MovieHandler) create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call the CreateMovie to add the new movie to the database.
-	id, err := h.MovieService.CreateMovie(movie)
-	if err != nil {
+	id, err := h.MovieService.CreateMovie(r.Context(), movie)
+	if fields := service.ErrorFields(err); fields != nil {
+		// Render the form again with the field errors and set status code.
+		render.HTML(w, http.StatusUnprocessableEntity, "movie/new.html", movieForm{movie, fields})
+		return
+	} else if err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if _, err := h.MovieService.GetMovie(id); err != nil {
+	if _, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Not Found", http.StatusNotFound)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 	} else {
 		http.Redirect(w, r, "/movies/"+strconv.FormatInt(id, 10), http.StatusCreated)
@@ -92,95 +169,141 @@ func (h *MovieHandler) create(w http.ResponseWriter, r *http.Request) {
 
 // Show responds to a request for a single movie.
 func (h *MovieHandler) show(w http.ResponseWriter, r *http.Request) {
-	// Parse the id param from the URL and convert it into an int64.
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	// Parse the id param from the URL.
+	id, err := movieID(r)
 	if err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Not Found", http.StatusNotFound)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if movie, err := h.MovieService.GetMovie(id); err != nil {
+	movie, err := h.MovieService.GetMovie(r.Context(), id)
+	if err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Not Found", http.StatusNotFound)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
-	} else {
+		return
+	}
+
+	// A movie in the trash is only shown with a way to restore it.
+	if movie.DeletedAt != nil {
 		// Render a HTML response and set status code.
-		render.HTML(w, http.StatusOK, "movie/show.html", movie)
+		render.HTML(w, http.StatusOK, "movie/show.html", movieShow{Movie: movie})
+		return
+	}
+
+	// Call GetReview to get the review of the movie, if it has one.
+	review, err := h.ReviewService.GetReview(r.Context(), id)
+	if err != nil && err != service.ErrReviewNotFound {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call GetCopies to get the copies of the movie that are owned.
+	copies, err := h.CopyService.GetCopies(r.Context(), id)
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
 	}
+
+	// Render a HTML response and set status code.
+	render.HTML(w, http.StatusOK, "movie/show.html", movieShow{movie, review, *copies})
 }
 
 // Edit responds to a request for entering details for a movie.
 func (h *MovieHandler) edit(w http.ResponseWriter, r *http.Request) {
-	// Parse the id param from the URL and convert it into an int64.
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	// Parse the id param from the URL.
+	id, err := movieID(r)
 	if err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Not Found", http.StatusNotFound)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if movie, err := h.MovieService.GetMovie(id); err != nil {
+	if movie, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Not Found", http.StatusNotFound)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 	} else {
 		// Render a HTML response and set status code.
-		render.HTML(w, http.StatusOK, "movie/edit.html", movie)
+		render.HTML(w, http.StatusOK, "movie/edit.html", movieForm{Movie: movie})
 	}
 }
 
 // Update responds to a request for updating a movie.
 func (h *MovieHandler) update(w http.ResponseWriter, r *http.Request) {
-	// Parse the id param from the URL and convert it into an int64.
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	// Parse the id param from the URL.
+	id, err := movieID(r)
 	if err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Not Found", http.StatusNotFound)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if _, err := h.MovieService.GetMovie(id); err != nil {
+	if _, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Not Found", http.StatusNotFound)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Parse the page form values.
-	err = r.ParseForm()
+	err = parseForm(r)
 	if err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Unprocessable Entity", http.StatusUnprocessableEntity)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Create a temporary movie struct to unmarshal the request body into.
+	// The version is the one the form was rendered with, so that changes
+	// made by someone else in the meantime are not overwritten.
+	version, _ := strconv.ParseInt(r.FormValue("version"), 10, 64)
 	movie := &service.Movie{
-		Title:  r.FormValue("title"),
-		ImdbID: r.FormValue("imdb_id"),
+		ID:      id,
+		Title:   r.FormValue("title"),
+		ImdbID:  r.FormValue("imdb_id"),
+		Version: version,
 	}
 
 	// Call UpdateMovie to update the movie in the database.
-	err = h.MovieService.UpdateMovie(id, movie)
-	if err != nil {
+	err = h.MovieService.UpdateMovie(r.Context(), id, movie)
+	if service.ErrorCode(err) == service.EPrecondition {
+		// Render the form again based on the latest version and set
+		// status code, so saving again is a deliberate overwrite.
+		if current, err := h.MovieService.GetMovie(r.Context(), id); err == nil {
+			movie.Version = current.Version
+		}
+		render.HTML(w, http.StatusConflict, "movie/edit.html", movieForm{movie, service.Validation{
+			"version": "Someone else changed this movie while you were editing it",
+		}})
+		return
+	} else if fields := service.ErrorFields(err); fields != nil {
+		// Render the form again with the field errors and set status code.
+		render.HTML(w, http.StatusUnprocessableEntity, "movie/edit.html", movieForm{movie, fields})
+		return
+	} else if err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if _, err := h.MovieService.GetMovie(id); err != nil {
+	if _, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Not Found", http.StatusNotFound)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 	} else {
 		http.Redirect(w, r, "/movies/"+strconv.FormatInt(id, 10), http.StatusCreated) // TODO(tim): FIX THIS
@@ -190,30 +313,51 @@ func (h *MovieHandler) update(w http.ResponseWriter, r *http.Request) {
 
 // Delete responds to a request for removing a movie.
 func (h *MovieHandler) delete(w http.ResponseWriter, r *http.Request) {
-	// Parse the id param from the URL and convert it into an int64.
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	// Parse the id param from the URL.
+	id, err := movieID(r)
 	if err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Not Found", http.StatusNotFound)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
 	// Call GetMovie to get the movie from the database.
-	if _, err := h.MovieService.GetMovie(id); err != nil {
+	if _, err := h.MovieService.GetMovie(r.Context(), id); err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Not Found", http.StatusNotFound)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 		return
 	}
 
-	// Call DeleteMovie to remove the movie from the database.
-	if err = h.MovieService.DeleteMovie(id); err != nil {
+	// Call DeleteMovie to move the movie to the trash.
+	if err = h.MovieService.DeleteMovie(r.Context(), id, 0); err != nil {
 		// Render an error response and set status code.
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		render.HTMLError(w, r, err)
 		log.Println("Error:", err)
 	} else {
 		http.Redirect(w, r, "/movies", http.StatusSeeOther) // TODO(tim): FIX THIS
 		return
 	}
 }
+
+// movieID parses the id param from the URL and converts it into an int64.
+// A malformed id is reported as a missing movie.
+func movieID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return 0, service.Errorf(service.ENotFound, "movie not found")
+	}
+
+	return id, nil
+}
+
+// parseForm parses the page form values, reporting a malformed form as a
+// bad request.
+func parseForm(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return &service.Error{Code: service.EBadRequest, Message: "invalid form", Err: err}
+	}
+
+	return nil
+}
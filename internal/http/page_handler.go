@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"../render"
+	"../service"
 	"github.com/go-chi/chi"
 )
 
@@ -24,5 +25,7 @@ func (h *PageHandler) Routes() chi.Router {
 
 // Index responds to a request for the site index page.
 func (h *PageHandler) index(w http.ResponseWriter, r *http.Request) {
-	render.HTML(w, http.StatusOK, "page/index.html", nil)
+	render.HTML(w, http.StatusOK, "page/index.html", struct {
+		User *service.User
+	}{service.UserFromContext(r.Context())})
 }
@@ -9,9 +9,20 @@ import (
 
 // Router ...
 type Router struct {
-	APIMovieHandler *api.MovieHandler
-	MovieHandler    *MovieHandler
-	PageHandler     *PageHandler
+	APIMovieHandler      *api.MovieHandler
+	APITrashHandler      *api.TrashHandler
+	APIDiaryHandler      *api.DiaryHandler
+	APIWatchlistHandler  *api.WatchlistHandler
+	APITagHandler        *api.TagHandler
+	APICollectionHandler *api.CollectionHandler
+	APICopyHandler       *api.CopyHandler
+	MovieHandler         *MovieHandler
+	TrashHandler         *TrashHandler
+	DiaryHandler         *DiaryHandler
+	PageHandler          *PageHandler
+	SessionHandler       *SessionHandler
+	TokenHandler         *TokenHandler
+	APITokenAuth         *api.TokenAuth
 }
 
 // Router ...
@@ -23,13 +34,32 @@ func (r *Router) Router() *chi.Mux {
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.DefaultCompress)
 
-	// Non-API routes
-	router.Mount("/", r.PageHandler.Routes())
-	router.Mount("/movies", r.MovieHandler.Routes())
+	// Non-API routes, where each library needs its signed in user
+	router.Group(func(sr chi.Router) {
+		sr.Use(r.SessionHandler.Authenticate)
 
-	// API (v1) routes
+		sr.Mount("/", r.PageHandler.Routes())
+		sr.With(RequireUser).Mount("/movies", r.MovieHandler.Routes())
+		sr.With(RequireUser).Mount("/trash", r.TrashHandler.Routes())
+		sr.With(RequireUser).Mount("/diary", r.DiaryHandler.Routes())
+		sr.Mount("/settings/tokens", r.TokenHandler.Routes())
+
+		sr.Get("/login", r.SessionHandler.new)
+		sr.Post("/login", r.SessionHandler.create)
+		sr.Post("/logout", r.SessionHandler.delete)
+	})
+
+	// API (v1) routes, which need a personal access token
 	router.Route("/api/v1", func(sr chi.Router) {
+		sr.Use(r.APITokenAuth.Authenticate)
+
 		sr.Mount("/movies", r.APIMovieHandler.Routes())
+		sr.Mount("/trash", r.APITrashHandler.Routes())
+		sr.Mount("/diary", r.APIDiaryHandler.Routes())
+		sr.Mount("/watchlist", r.APIWatchlistHandler.Routes())
+		sr.Mount("/tags", r.APITagHandler.Routes())
+		sr.Mount("/collections", r.APICollectionHandler.Routes())
+		sr.Mount("/copies", r.APICopyHandler.Routes())
 	})
 
 	return router
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 	"net/http"
@@ -11,68 +12,156 @@ import (
 
 // Server is a structure that contains the pieces that make up a server.
 type Server struct {
-	Router      *chi.Mux
+	Router    *chi.Mux
+	HTTPAddr  string // e.g. ":8080"
+	HTTPSAddr string // e.g. ":8081"
+	CertFile  string
+	KeyFile   string
+
+	// PlainHTTP serves the routes over HTTP only, for running behind a
+	// reverse proxy that terminates TLS.
+	PlainHTTP bool
+
+	// SelfSigned generates a self-signed certificate at CertFile and
+	// KeyFile on start if there isn't a valid one there already.
+	SelfSigned bool
+
+	// ShutdownTimeout is how long to wait for in-flight requests to
+	// finish when the server is stopped.
+	ShutdownTimeout time.Duration
+
 	httpsServer *http.Server
 	httpServer  *http.Server
 }
 
 // Run kicks everything off by setting the routes and launching the HTTP
-// and HTTPS servers in goroutines. It returns an error if something fails.
-func (srv *Server) Run() error {
-	errs := make(chan error, 1)
+// and HTTPS servers in goroutines. With PlainHTTP set only the HTTP server
+// is launched and it serves the routes itself. It runs until ctx is
+// cancelled or a server fails, then gracefully shuts down the servers,
+// waiting up to ShutdownTimeout for in-flight requests to finish. It
+// returns an error if something fails.
+func (srv *Server) Run(ctx context.Context) error {
+	errs := make(chan error, 2)
+
+	if srv.PlainHTTP {
+		srv.httpServer = srv.newServer(srv.HTTPAddr, srv.Router)
+		go func() { errs <- srv.httpServer.ListenAndServe() }()
+	} else {
+		if srv.SelfSigned {
+			if err := selfSignedCert(srv.CertFile, srv.KeyFile, srv.HTTPSAddr); err != nil {
+				return err
+			}
+		}
+
+		httpServer, err := srv.newHTTPServer()
+		if err != nil {
+			return err
+		}
+		srv.httpServer = httpServer
+		srv.httpsServer = srv.newHTTPSServer()
 
-	go srv.newHTTPServer(errs)
-	go srv.newHTTPSServer("localhost.pem", "localhost-key.pem", errs)
+		go func() { errs <- srv.httpServer.ListenAndServe() }()
+		go func() { errs <- srv.httpsServer.ListenAndServeTLS(srv.CertFile, srv.KeyFile) }()
+	}
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+	}
 
-	return <-errs
+	if serr := srv.shutdown(); err == nil {
+		err = serr
+	}
+
+	return err
 }
 
-// newHTTPServer configures and starts the HTTP server.
-// It sends any errors via a channel if the server fails to start.
-func (srv *Server) newHTTPServer(errs chan<- error) {
-	srv.httpServer = &http.Server{
-		Addr:         ":8080", // TODO(tim): Make this configurable
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
-		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			host, _, err := net.SplitHostPort(r.Host)
-			if err != nil {
+// shutdown gracefully stops the running servers, waiting up to
+// ShutdownTimeout for in-flight requests to finish before closing their
+// connections.
+func (srv *Server) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), srv.ShutdownTimeout)
+	defer cancel()
+
+	var servers []*http.Server
+	for _, s := range []*http.Server{srv.httpServer, srv.httpsServer} {
+		if s != nil {
+			servers = append(servers, s)
+		}
+	}
+
+	errs := make(chan error, len(servers))
+	for _, s := range servers {
+		go func(s *http.Server) {
+			if err := s.Shutdown(ctx); err != nil {
+				s.Close()
 				errs <- err
+				return
 			}
-			url := r.URL
-			url.Host = net.JoinHostPort(host, "8081") // TODO(tim): Make this configurable
-			url.Scheme = "https"
-			http.Redirect(w, r, url.String(), http.StatusMovedPermanently)
-		}),
+			errs <- nil
+		}(s)
 	}
 
-	errs <- srv.httpServer.ListenAndServe()
+	var err error
+	for range servers {
+		if serr := <-errs; err == nil {
+			err = serr
+		}
+	}
+
+	return err
 }
 
-// newHTTPSServer configures and starts the HTTP server.
-// It sends any errors via a channel if the server fails to start.
-func (srv *Server) newHTTPSServer(certFile, keyFile string, errs chan<- error) {
-	srv.httpsServer = &http.Server{
-		Addr:         ":8081", // TODO(tim): Make this configurable
+// newServer configures a server listening on addr with the timeouts
+// shared by every server.
+func (srv *Server) newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         addr,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
-		Handler:      srv.Router,
-		TLSConfig: &tls.Config{
-			PreferServerCipherSuites: true,
-			CurvePreferences:         []tls.CurveID{tls.CurveP256, tls.X25519},
-			MinVersion:               tls.VersionTLS12,
-			CipherSuites: []uint16{
-				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
-				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
-				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-			},
+		Handler:      handler,
+	}
+}
+
+// newHTTPServer configures the HTTP server, which redirects every
+// request to the HTTPS server.
+func (srv *Server) newHTTPServer() (*http.Server, error) {
+	_, httpsPort, err := net.SplitHostPort(srv.HTTPSAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return srv.newServer(srv.HTTPAddr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The Host header only includes a port if it isn't the default.
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		url := r.URL
+		url.Host = net.JoinHostPort(host, httpsPort)
+		url.Scheme = "https"
+		http.Redirect(w, r, url.String(), http.StatusMovedPermanently)
+	})), nil
+}
+
+// newHTTPSServer configures the HTTPS server, which serves the router.
+func (srv *Server) newHTTPSServer() *http.Server {
+	s := srv.newServer(srv.HTTPSAddr, srv.Router)
+	s.TLSConfig = &tls.Config{
+		PreferServerCipherSuites: true,
+		CurvePreferences:         []tls.CurveID{tls.CurveP256, tls.X25519},
+		MinVersion:               tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
 		},
 	}
 
-	errs <- srv.httpsServer.ListenAndServeTLS(certFile, keyFile)
+	return s
 }
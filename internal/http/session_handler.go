@@ -0,0 +1,173 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"../render"
+	"../service"
+)
+
+// sessionCookie is the name of the cookie holding the session token.
+const sessionCookie = "pmdb_session"
+
+// SessionHandler signs users in and out of the HTML site using cookie
+// sessions stored by the user service.
+type SessionHandler struct {
+	UserService service.UserService
+
+	// MaxAge is how long a session lasts after signing in.
+	MaxAge time.Duration
+
+	// Secure limits the session cookie to HTTPS. It should only be unset
+	// when the server runs without TLS.
+	Secure bool
+}
+
+// loginForm is the data for the login page.
+type loginForm struct {
+	Username string
+	Next     string
+	Error    string
+}
+
+// Authenticate is middleware that loads the signed in user from the
+// session cookie into the request context. Requests without a valid
+// session carry on without a user.
+func (h *SessionHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookie)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, err := h.UserService.GetSessionUser(r.Context(), cookie.Value)
+		if err == service.ErrSessionNotFound {
+			h.clearCookie(w)
+		} else if err != nil {
+			log.Println("Error:", err)
+		} else {
+			r = r.WithContext(service.NewUserContext(r.Context(), user))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireUser is middleware that redirects requests without a signed in
+// user to the login page, returning to the requested page afterwards.
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if service.UserFromContext(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Only pages can be returned to; a form submission is lost.
+		path := "/login"
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			path += "?" + url.Values{"next": {r.URL.RequestURI()}}.Encode()
+		}
+		http.Redirect(w, r, path, http.StatusSeeOther)
+	})
+}
+
+// New responds to a request for the login page.
+func (h *SessionHandler) new(w http.ResponseWriter, r *http.Request) {
+	// Render a HTML response and set status code.
+	render.HTML(w, http.StatusOK, "session/new.html", loginForm{Next: safeNext(r.URL.Query().Get("next"))})
+}
+
+// Create responds to a request for signing in.
+func (h *SessionHandler) create(w http.ResponseWriter, r *http.Request) {
+	// Parse the page form values.
+	err := parseForm(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	form := loginForm{
+		Username: r.FormValue("username"),
+		Next:     safeNext(r.FormValue("next")),
+	}
+
+	// Call Authenticate to check the username and password.
+	user, err := h.UserService.Authenticate(r.Context(), form.Username, r.FormValue("password"))
+	if err == service.ErrInvalidCredentials {
+		// Render the form again with the error and set status code.
+		form.Error = service.ErrorMessage(err)
+		render.HTML(w, http.StatusUnauthorized, "session/new.html", form)
+		return
+	} else if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call CreateSession to start a session for the user.
+	session, err := h.UserService.CreateSession(r.Context(), user.ID, h.MaxAge)
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		Secure:   h.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, form.Next, http.StatusSeeOther)
+}
+
+// Delete responds to a request for signing out.
+func (h *SessionHandler) delete(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		// Call DeleteSession to end the session.
+		if err := h.UserService.DeleteSession(r.Context(), cookie.Value); err != nil {
+			// Render an error response and set status code.
+			render.HTMLError(w, r, err)
+			log.Println("Error:", err)
+			return
+		}
+	}
+
+	h.clearCookie(w)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// clearCookie tells the browser to remove the session cookie.
+func (h *SessionHandler) clearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   h.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// safeNext returns the page to go to after signing in. Only paths on this
+// site are allowed, so the login page cannot be used to redirect users
+// elsewhere.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/movies"
+	}
+
+	return next
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"../render"
+	"../service"
+	"github.com/go-chi/chi"
+)
+
+// tokenExpiries lists the lifetimes offered for a new token, in days. A
+// lifetime of 0 means the token never expires.
+var tokenExpiries = []int{7, 30, 90, 365, 0}
+
+// TokenHandler lets a signed in user manage their personal access tokens
+// for the API. Every route requires a signed in user.
+type TokenHandler struct {
+	TokenService service.TokenService
+}
+
+// tokenPage is the data for the token settings page. Secret is only set
+// right after a token is created, as it cannot be shown again.
+type tokenPage struct {
+	Tokens   *service.Tokens
+	Expiries []int
+	Form     *service.Token
+	Days     int
+	Secret   string
+	Errors   service.Validation
+}
+
+// Routes creates a REST router for the token handler.
+func (h *TokenHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Load middleware specific to this router.
+	r.Use(RequireUser)
+
+	r.Get("/", h.index)
+	r.Post("/", h.create)
+	r.Post("/{id}/revoke", h.revoke)
+
+	return r
+}
+
+// Index responds to a request for a list of the user's tokens.
+func (h *TokenHandler) index(w http.ResponseWriter, r *http.Request) {
+	page := tokenPage{Form: &service.Token{Scope: service.ScopeRead}, Days: 30}
+	h.render(w, r, http.StatusOK, page)
+}
+
+// Create responds to a request for adding a token.
+func (h *TokenHandler) create(w http.ResponseWriter, r *http.Request) {
+	// Parse the page form values.
+	err := parseForm(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	token := &service.Token{
+		UserID: service.UserFromContext(r.Context()).ID,
+		Name:   r.FormValue("name"),
+		Scope:  r.FormValue("scope"),
+	}
+	days, _ := strconv.Atoi(r.FormValue("expires"))
+	if days > 0 {
+		expiresAt := time.Now().AddDate(0, 0, days)
+		token.ExpiresAt = &expiresAt
+	}
+
+	// Call CreateToken to add the new token to the database.
+	secret, err := h.TokenService.CreateToken(r.Context(), token)
+	if fields := service.ErrorFields(err); fields != nil {
+		// Render the form again with the field errors and set status code.
+		h.render(w, r, http.StatusUnprocessableEntity, tokenPage{Form: token, Days: days, Errors: fields})
+		return
+	} else if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	page := tokenPage{Form: &service.Token{Scope: service.ScopeRead}, Days: 30, Secret: secret}
+	h.render(w, r, http.StatusCreated, page)
+}
+
+// Revoke responds to a request for revoking a token.
+func (h *TokenHandler) revoke(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		err = service.Errorf(service.ENotFound, "token not found")
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call RevokeToken to stop the token from being used.
+	user := service.UserFromContext(r.Context())
+	if err := h.TokenService.RevokeToken(r.Context(), user.ID, id); err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		http.Redirect(w, r, "/settings/tokens", http.StatusSeeOther)
+	}
+}
+
+// render renders the token settings page with the user's tokens.
+func (h *TokenHandler) render(w http.ResponseWriter, r *http.Request, status int, page tokenPage) {
+	// Call GetTokens to retrieve the user's tokens from the database.
+	user := service.UserFromContext(r.Context())
+	tokens, err := h.TokenService.GetTokens(r.Context(), user.ID)
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	page.Tokens = tokens
+	page.Expiries = tokenExpiries
+
+	// Render a HTML response and set status code.
+	render.HTML(w, status, "token/index.html", page)
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"../render"
+	"../service"
+	"github.com/go-chi/chi"
+)
+
+// TrashHandler ...
+type TrashHandler struct {
+	MovieService service.MovieService
+}
+
+// Routes creates a REST router for the trash handler.
+func (h *TrashHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Load middleware specific to this router.
+	// r.Use()
+
+	r.Get("/", h.index)
+	r.Post("/empty", h.empty)
+	r.Post("/{id}/restore", h.restore)
+	r.Post("/{id}/purge", h.purge)
+
+	return r
+}
+
+// Index responds to a request for a list of the movies in the trash.
+func (h *TrashHandler) index(w http.ResponseWriter, r *http.Request) {
+	// Parse the pagination and sorting query parameters.
+	filter, err := service.ParseMovieFilter(r.URL.Query())
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+	filter.Trashed = true
+
+	// Call GetMovies to retrieve a page of trashed movies from the database.
+	movies, next, err := h.MovieService.GetMovies(r.Context(), filter)
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Link to the next page if there is one.
+	var nextURL string
+	if next != nil {
+		nextURL = "/trash?" + filter.Next(next).Query().Encode()
+	}
+
+	// Render a HTML response and set status code.
+	render.HTML(w, http.StatusOK, "trash/index.html", struct {
+		Movies *service.Movies
+		Next   string
+	}{movies, nextURL})
+}
+
+// Restore responds to a request for moving a movie out of the trash.
+func (h *TrashHandler) restore(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call RestoreMovie to move the movie back into the library.
+	if err := h.MovieService.RestoreMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		http.Redirect(w, r, "/movies/"+strconv.FormatInt(id, 10), http.StatusSeeOther)
+	}
+}
+
+// Purge responds to a request for permanently removing a movie in the
+// trash.
+func (h *TrashHandler) purge(w http.ResponseWriter, r *http.Request) {
+	// Parse the id param from the URL.
+	id, err := movieID(r)
+	if err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+		return
+	}
+
+	// Call PurgeMovie to remove the movie from the database.
+	if err := h.MovieService.PurgeMovie(r.Context(), id); err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		http.Redirect(w, r, "/trash", http.StatusSeeOther)
+	}
+}
+
+// Empty responds to a request for permanently removing every movie in the
+// trash.
+func (h *TrashHandler) empty(w http.ResponseWriter, r *http.Request) {
+	// Call EmptyTrash to remove the movies from the database.
+	if _, err := h.MovieService.EmptyTrash(r.Context()); err != nil {
+		// Render an error response and set status code.
+		render.HTMLError(w, r, err)
+		log.Println("Error:", err)
+	} else {
+		http.Redirect(w, r, "/trash", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,705 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"../service"
+)
+
+// Ensure MovieService implements service.MovieService.
+var _ service.MovieService = &MovieService{}
+
+// MovieService represents an in-memory implementation of a MovieService.
+// It mirrors the behaviour of the SQLite implementation, including its
+// constraints, and is safe for concurrent use. The zero value is ready
+// to use. Calls fail with the context's error if it is already done.
+type MovieService struct {
+	mu     sync.RWMutex
+	movies []*service.Movie
+	lastID int64
+
+	history       service.History
+	lastHistoryID int64
+}
+
+// GetMovies returns the movies in the store matching the filter, along
+// with a cursor for the next page if there are more movies.
+func (s *MovieService) GetMovies(ctx context.Context, f service.MovieFilter) (*service.Movies, *service.Cursor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var movies service.Movies
+	for _, m := range s.movies {
+		if !visible(ctx, m) || !matches(f, m) {
+			continue
+		}
+		movie := *m
+		movies = append(movies, &movie)
+	}
+
+	sort.SliceStable(movies, func(i, j int) bool {
+		return compare(f, service.NewCursor(f, movies[i]), service.NewCursor(f, movies[j])) < 0
+	})
+
+	// Skip every movie up to and including the cursor position.
+	if f.After != nil {
+		if f.SortBy == service.SortByCreatedAt || f.SortBy == service.SortByUpdatedAt {
+			if _, err := f.After.Time(); err != nil {
+				return nil, nil, service.ErrInvalidCursor
+			}
+		}
+
+		i := sort.Search(len(movies), func(i int) bool {
+			return compare(f, service.NewCursor(f, movies[i]), f.After) > 0
+		})
+		movies = movies[i:]
+	}
+
+	var next *service.Cursor
+	if f.Limit > 0 && len(movies) > f.Limit {
+		movies = movies[:f.Limit]
+		next = service.NewCursor(f, movies[len(movies)-1])
+	}
+
+	return &movies, next, nil
+}
+
+// GetMovie returns a single movie from the store.
+func (s *MovieService) GetMovie(ctx context.Context, id int64) (*service.Movie, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.index(ctx, id)
+	if i < 0 {
+		return nil, service.Errorf(service.ENotFound, "movie not found")
+	}
+
+	movie := *s.movies[i]
+	return &movie, nil
+}
+
+// GetMovieByImdbID returns the movie with the given IMDb id from the store.
+func (s *MovieService) GetMovieByImdbID(ctx context.Context, imdbID string) (*service.Movie, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, m := range s.movies {
+		if m.ImdbID == imdbID && visible(ctx, m) {
+			movie := *m
+			return &movie, nil
+		}
+	}
+
+	return nil, service.Errorf(service.ENotFound, "movie not found")
+}
+
+// SearchMovies returns the movies in the store matching the search query,
+// most relevant first. Every term in the query must match the start of a
+// word in the title, director, cast or plot. Movies are ranked by how many
+// words match, with lower ranks being more relevant as in SQLite.
+func (s *MovieService) SearchMovies(ctx context.Context, query string, limit int) (*service.SearchResults, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := service.SearchTerms(strings.ToLower(query))
+
+	results := service.SearchResults{}
+	if len(terms) == 0 {
+		return &results, nil
+	}
+
+	for _, m := range s.movies {
+		if m.DeletedAt != nil || !visible(ctx, m) {
+			continue
+		}
+
+		// Every term must match the start of at least one word in one
+		// of the searchable fields.
+		fields := []string{m.Title, m.Director, strings.Join(m.Cast, ", "), m.Plot}
+		words := service.SearchTerms(strings.ToLower(strings.Join(fields, " ")))
+		found := true
+		for _, t := range terms {
+			if !hasPrefix(words, t) {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		// Use the field with the most matches as the snippet.
+		var snippet string
+		best := 0
+		for _, f := range fields {
+			if h, n := highlight(f, terms); n > best {
+				snippet, best = h, n
+			}
+		}
+
+		movie := *m
+		results = append(results, &service.SearchResult{
+			Movie:   &movie,
+			Rank:    -float64(best),
+			Snippet: service.MarkSnippet(snippet),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank < results[j].Rank
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return &results, nil
+}
+
+// CreateMovie adds a new movie to the store.
+func (s *MovieService) CreateMovie(ctx context.Context, movie *service.Movie) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner := service.UserIDFromContext(ctx)
+	if err := s.check(0, owner, movie); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	s.lastID++
+	s.movies = append(s.movies, &service.Movie{
+		ID:        s.lastID,
+		Title:     movie.Title,
+		ImdbID:    movie.ImdbID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+		OwnerID:   owner,
+	})
+	s.record(ctx, service.HistoryCreate, nil, s.movies[len(s.movies)-1])
+
+	return s.lastID, nil
+}
+
+// UpdateMovie updates an existing movie in the store. Like the SQLite
+// implementation it does nothing if the movie does not exist.
+func (s *MovieService) UpdateMovie(ctx context.Context, id int64, movie *service.Movie) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(ctx, id)
+	if i < 0 {
+		if movie.Version != 0 {
+			return service.ErrMovieChanged
+		}
+		return nil
+	}
+
+	if movie.Version != 0 && movie.Version != s.movies[i].Version {
+		return service.ErrMovieChanged
+	}
+	if err := s.check(id, s.movies[i].OwnerID, movie); err != nil {
+		return err
+	}
+
+	before := *s.movies[i]
+	s.movies[i].Title = movie.Title
+	s.movies[i].ImdbID = movie.ImdbID
+	s.movies[i].UpdatedAt = time.Now()
+	s.movies[i].Version++
+	s.record(ctx, service.HistoryUpdate, &before, s.movies[i])
+
+	return nil
+}
+
+// UpsertMovie creates a movie, or updates the title of the movie with the
+// same IMDb id if there is one, restoring it if it is in the trash. It
+// reports whether the movie was created.
+func (s *MovieService) UpsertMovie(ctx context.Context, movie *service.Movie) (int64, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	movie.Normalize()
+	if err := movie.Validate(); err != nil {
+		return 0, false, err
+	}
+
+	owner := service.UserIDFromContext(ctx)
+	for _, m := range s.movies {
+		if m.ImdbID == movie.ImdbID && m.OwnerID == owner {
+			if movie.Version != 0 && movie.Version != m.Version {
+				return 0, false, service.ErrMovieChanged
+			}
+			before := *m
+			m.Title = movie.Title
+			m.UpdatedAt = time.Now()
+			m.DeletedAt = nil
+			m.Version++
+			s.record(ctx, service.HistoryUpdate, &before, m)
+			return m.ID, false, nil
+		}
+	}
+
+	if movie.Version != 0 {
+		return 0, false, service.ErrMovieChanged
+	}
+
+	now := time.Now()
+	s.lastID++
+	s.movies = append(s.movies, &service.Movie{
+		ID:        s.lastID,
+		Title:     movie.Title,
+		ImdbID:    movie.ImdbID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+		OwnerID:   owner,
+	})
+	s.record(ctx, service.HistoryCreate, nil, s.movies[len(s.movies)-1])
+
+	return s.lastID, true, nil
+}
+
+// PatchMovie updates only the fields of an existing movie in the store
+// that are included in the update. The patched movie is validated as a
+// whole.
+func (s *MovieService) PatchMovie(ctx context.Context, id int64, u *service.MovieUpdate) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(ctx, id)
+	if i < 0 {
+		return service.Errorf(service.ENotFound, "movie not found")
+	}
+
+	if u.Version != 0 && u.Version != s.movies[i].Version {
+		return service.ErrMovieChanged
+	}
+
+	return s.patch(ctx, service.HistoryUpdate, i, u)
+}
+
+// UpdateMetadata replaces the external metadata of an existing movie in
+// the store and records when it was fetched.
+func (s *MovieService) UpdateMetadata(ctx context.Context, id int64, md *service.Metadata) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(ctx, id)
+	if i < 0 {
+		return nil
+	}
+
+	before := *s.movies[i]
+	now := time.Now()
+	s.movies[i].Metadata = *md
+	s.movies[i].EnrichedAt = &now
+	s.movies[i].UpdatedAt = now
+	s.movies[i].Version++
+	s.record(ctx, service.HistoryEnrich, &before, s.movies[i])
+
+	return nil
+}
+
+// DeleteMovie moves an existing movie in the store to the trash.
+func (s *MovieService) DeleteMovie(ctx context.Context, id int64, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(ctx, id)
+	if i < 0 || s.movies[i].DeletedAt != nil {
+		return service.Errorf(service.ENotFound, "movie not found")
+	}
+	if version != 0 && version != s.movies[i].Version {
+		return service.ErrMovieChanged
+	}
+
+	before := *s.movies[i]
+	now := time.Now()
+	s.movies[i].DeletedAt = &now
+	s.movies[i].Version++
+	s.record(ctx, service.HistoryDelete, &before, s.movies[i])
+
+	return nil
+}
+
+// RestoreMovie moves a movie in the trash back into the library. Restoring
+// a movie that is not in the trash does nothing.
+func (s *MovieService) RestoreMovie(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(ctx, id)
+	if i < 0 {
+		return service.Errorf(service.ENotFound, "movie not found")
+	}
+
+	if s.movies[i].DeletedAt != nil {
+		before := *s.movies[i]
+		s.movies[i].DeletedAt = nil
+		s.movies[i].Version++
+		s.record(ctx, service.HistoryRestore, &before, s.movies[i])
+	}
+
+	return nil
+}
+
+// PurgeMovie permanently removes a movie in the trash from the store.
+func (s *MovieService) PurgeMovie(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(ctx, id)
+	if i < 0 || s.movies[i].DeletedAt == nil {
+		return service.ErrNotInTrash
+	}
+
+	s.record(ctx, service.HistoryPurge, s.movies[i], nil)
+	s.movies = append(s.movies[:i], s.movies[i+1:]...)
+	return nil
+}
+
+// EmptyTrash permanently removes every movie in the trash from the store
+// and returns how many were removed.
+func (s *MovieService) EmptyTrash(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	movies := s.movies[:0]
+	for _, m := range s.movies {
+		if m.DeletedAt == nil || !visible(ctx, m) {
+			movies = append(movies, m)
+		} else {
+			s.record(ctx, service.HistoryPurge, m, nil)
+		}
+	}
+	n := int64(len(s.movies) - len(movies))
+	s.movies = movies
+
+	return n, nil
+}
+
+// GetHistory returns the recorded changes to a movie in the store, most
+// recent first. The history of a purged movie is still returned.
+func (s *MovieService) GetHistory(ctx context.Context, id int64) (*service.History, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	owner := service.UserIDFromContext(ctx)
+	history := service.History{}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].MovieID == id && (owner == 0 || s.history[i].OwnerID == owner) {
+			entry := *s.history[i]
+			history = append(history, &entry)
+		}
+	}
+
+	if len(history) == 0 && s.index(ctx, id) < 0 {
+		return nil, service.Errorf(service.ENotFound, "movie not found")
+	}
+
+	return &history, nil
+}
+
+// RevertMovie sets the title, IMDb id and metadata of an existing movie in
+// the store back to how they were at a version in its history.
+func (s *MovieService) RevertMovie(ctx context.Context, id int64, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(ctx, id)
+	if i < 0 {
+		return service.Errorf(service.ENotFound, "movie not found")
+	}
+
+	for j := len(s.history) - 1; j >= 0; j-- {
+		entry := s.history[j]
+		if entry.MovieID == id && entry.Version == version && entry.After != nil {
+			return s.patch(ctx, service.HistoryRevert, i, service.NewMovieUpdate(s.movies[i], entry.After))
+		}
+	}
+
+	return service.ErrVersionNotFound
+}
+
+// patch applies an update to the movie at position i and records it in the
+// history as action. The caller must hold the lock.
+func (s *MovieService) patch(ctx context.Context, action string, i int, u *service.MovieUpdate) error {
+	before := s.movies[i]
+	movie := *before
+	u.Apply(&movie)
+	if err := s.check(movie.ID, movie.OwnerID, &movie); err != nil {
+		return err
+	}
+
+	if *u != (service.MovieUpdate{Version: u.Version}) {
+		movie.UpdatedAt = time.Now()
+		movie.Version++
+		s.movies[i] = &movie
+		s.record(ctx, action, before, &movie)
+	}
+
+	return nil
+}
+
+// record adds a change from before to after, made by the actor from ctx,
+// to the history. The caller must hold the lock.
+func (s *MovieService) record(ctx context.Context, action string, before, after *service.Movie) {
+	entry := &service.HistoryEntry{
+		Action:    action,
+		Actor:     service.ActorFromContext(ctx),
+		CreatedAt: time.Now(),
+	}
+	if before != nil {
+		m := *before
+		entry.Before = &m
+		entry.MovieID, entry.Version, entry.OwnerID = m.ID, m.Version, m.OwnerID
+	}
+	if after != nil {
+		m := *after
+		entry.After = &m
+		entry.MovieID, entry.Version, entry.OwnerID = m.ID, m.Version, m.OwnerID
+	}
+
+	s.lastHistoryID++
+	entry.ID = s.lastHistoryID
+	s.history = append(s.history, entry)
+}
+
+// index returns the position of the movie with the given id, or -1 if
+// it is not in the store or not visible to the user in ctx. The caller
+// must hold the lock.
+func (s *MovieService) index(ctx context.Context, id int64) int {
+	for i, m := range s.movies {
+		if m.ID == id && visible(ctx, m) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// check validates a movie being written with the given id (0 for a new
+// movie) to the library of owner and enforces the same constraints as the
+// movies table. The caller must hold the lock.
+func (s *MovieService) check(id, owner int64, movie *service.Movie) error {
+	movie.Normalize()
+	if err := movie.Validate(); err != nil {
+		return err
+	}
+
+	for _, m := range s.movies {
+		if m.ID != id && m.OwnerID == owner && m.ImdbID == movie.ImdbID {
+			return service.Errorf(service.EConflict, "a movie with that IMDb id already exists")
+		}
+	}
+
+	return nil
+}
+
+// visible reports whether the movie is in the library of the user in ctx,
+// or ctx has no user.
+func visible(ctx context.Context, m *service.Movie) bool {
+	owner := service.UserIDFromContext(ctx)
+	return owner == 0 || m.OwnerID == owner
+}
+
+// matches reports whether the movie satisfies the filter conditions.
+func matches(f service.MovieFilter, m *service.Movie) bool {
+	if (m.DeletedAt != nil) != f.Trashed {
+		return false
+	}
+	if f.TitlePrefix != "" &&
+		!strings.HasPrefix(strings.ToLower(m.Title), strings.ToLower(f.TitlePrefix)) {
+		return false
+	}
+	if f.Tag != "" && !hasTag(m, f.Tag) {
+		return false
+	}
+	// Collections are not kept in memory, so no movie is in one.
+	if f.Collection != 0 {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && m.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !m.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	if !f.UpdatedAfter.IsZero() && m.UpdatedAt.Before(f.UpdatedAfter) {
+		return false
+	}
+	if !f.UpdatedBefore.IsZero() && !m.UpdatedAt.Before(f.UpdatedBefore) {
+		return false
+	}
+	if !f.EnrichedBefore.IsZero() &&
+		(m.EnrichedAt == nil || !m.EnrichedAt.Before(f.EnrichedBefore)) {
+		return false
+	}
+
+	return true
+}
+
+// hasTag reports whether the movie has the tag, ignoring case.
+func hasTag(m *service.Movie, tag string) bool {
+	for _, t := range m.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compare orders two cursor positions in the filter's sort order. It
+// returns a negative number if a comes first, a positive number if b
+// comes first and zero if they are the same position.
+func compare(f service.MovieFilter, a, b *service.Cursor) int {
+	c := 0
+	switch f.SortBy {
+	case service.SortByTitle:
+		c = strings.Compare(strings.ToLower(a.Value), strings.ToLower(b.Value))
+	case service.SortByCreatedAt, service.SortByUpdatedAt:
+		at, _ := a.Time()
+		bt, _ := b.Time()
+		switch {
+		case at.Before(bt):
+			c = -1
+		case at.After(bt):
+			c = 1
+		}
+	}
+
+	if c == 0 {
+		switch {
+		case a.ID < b.ID:
+			c = -1
+		case a.ID > b.ID:
+			c = 1
+		}
+	}
+
+	if f.Desc {
+		return -c
+	}
+
+	return c
+}
+
+// hasPrefix reports whether any of the words start with prefix.
+func hasPrefix(words []string, prefix string) bool {
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// highlight puts snippet markers around every word in s that starts with
+// one of the terms. It returns the result and the number of words wrapped.
+func highlight(s string, terms []string) (string, int) {
+	var b strings.Builder
+	n := 0
+	word := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+	for len(s) > 0 {
+		i := strings.IndexFunc(s, word)
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i])
+		s = s[i:]
+
+		j := strings.IndexFunc(s, func(r rune) bool { return !word(r) })
+		if j < 0 {
+			j = len(s)
+		}
+		w := s[:j]
+		s = s[j:]
+
+		matched := false
+		for _, t := range terms {
+			if strings.HasPrefix(strings.ToLower(w), t) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			b.WriteString(service.SnippetStart + w + service.SnippetEnd)
+			n++
+		} else {
+			b.WriteString(w)
+		}
+	}
+
+	return b.String(), n
+}
@@ -0,0 +1,166 @@
+package omdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"../service"
+)
+
+// DefaultBaseURL is the address of the public OMDb API.
+const DefaultBaseURL = "https://www.omdbapi.com/"
+
+// Ensure Client implements service.MetadataService.
+var _ service.MetadataService = &Client{}
+
+// Client represents an OMDb implementation of a MetadataService.
+type Client struct {
+	// BaseURL is the address of the OMDb API. It defaults to
+	// DefaultBaseURL and can be pointed at a fake server for testing.
+	BaseURL string
+
+	// APIKey is the OMDb API key sent with every request.
+	APIKey string
+
+	// HTTPClient is used to make requests. It defaults to a client
+	// with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+// movie is the subset of an OMDb title response used by the client.
+type movie struct {
+	Response string `json:"Response"`
+	Error    string `json:"Error"`
+	Year     string `json:"Year"`
+	Runtime  string `json:"Runtime"`
+	Genre    string `json:"Genre"`
+	Director string `json:"Director"`
+	Actors   string `json:"Actors"`
+	Plot     string `json:"Plot"`
+	Poster   string `json:"Poster"`
+}
+
+// defaultHTTPClient is used when a Client has no HTTPClient set.
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// GetMetadata fetches the metadata for the movie with the given IMDb id.
+// It returns service.ErrMetadataNotFound if OMDb does not know the movie.
+func (c *Client) GetMetadata(ctx context.Context, imdbID string) (*service.Metadata, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("apikey", c.APIKey)
+	q.Set("i", imdbID)
+	q.Set("plot", "short")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = defaultHTTPClient
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, upstream(err)
+	}
+	defer res.Body.Close()
+
+	// OMDb reports most failures, including unknown movies, with a 200
+	// status and an error message in the body.
+	var m movie
+	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+		return nil, upstream(fmt.Errorf("%s: %v", res.Status, err))
+	}
+
+	if m.Response != "True" {
+		if m.Error == "Incorrect IMDb ID." || m.Error == "Movie not found!" {
+			return nil, service.ErrMetadataNotFound
+		}
+		if m.Error == "" {
+			m.Error = res.Status
+		}
+		return nil, upstream(errors.New(m.Error))
+	}
+
+	return &service.Metadata{
+		Year:      leadingInt(value(m.Year)),
+		Runtime:   leadingInt(value(m.Runtime)),
+		Genres:    list(value(m.Genre)),
+		Director:  value(m.Director),
+		Cast:      list(value(m.Actors)),
+		Plot:      value(m.Plot),
+		PosterURL: posterURL(value(m.Poster)),
+	}, nil
+}
+
+// upstream wraps an error from OMDb as a service error.
+func upstream(err error) error {
+	return &service.Error{
+		Code:    service.EUpstream,
+		Message: "OMDb request failed",
+		Err:     err,
+	}
+}
+
+// value returns s, or an empty string if OMDb marked it as not available.
+func value(s string) string {
+	if s == "N/A" {
+		return ""
+	}
+
+	return strings.TrimSpace(s)
+}
+
+// posterURL returns s if it is an http or https URL, otherwise an empty
+// string, so a poster can never be linked with another scheme.
+func posterURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+
+	return s
+}
+
+// list splits an OMDb comma separated list, such as "Action, Drama".
+func list(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// leadingInt parses the number at the start of s, such as the 181 in
+// "181 min" or the 2019 in "2019–2020". It returns 0 if there is none.
+func leadingInt(s string) int {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
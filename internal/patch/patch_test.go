@@ -0,0 +1,213 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// errAny matches any error in the test tables.
+var errAny = errors.New("any error")
+
+// checkResult fails the test unless got and err match the wanted JSON
+// document or error.
+func checkResult(t *testing.T, got []byte, err error, want string, wantErr error) {
+	t.Helper()
+
+	switch {
+	case wantErr == errAny && err == nil:
+		t.Fatalf("got %s, want an error", got)
+	case wantErr != nil && wantErr != errAny && !errors.Is(err, wantErr):
+		t.Fatalf("error = %v, want %v", err, wantErr)
+	case wantErr == nil && err != nil:
+		t.Fatalf("error = %v", err)
+	case wantErr != nil:
+		return
+	}
+
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("result %s is not JSON: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("want %s is not JSON: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// TestMerge runs the examples from Appendix A of RFC 7396.
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+		err              error
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`, nil},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`, nil},
+		{`{"a":"b"}`, `{"a":null}`, `{}`, nil},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`, nil},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`, nil},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`, nil},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`, nil},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`, nil},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`, nil},
+		{`{"a":"b"}`, `["c"]`, `["c"]`, nil},
+		{`{"a":"foo"}`, `null`, `null`, nil},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`, nil},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`, nil},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`, nil},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`, nil},
+		{`{"a":"b"}`, `{"a":`, ``, ErrInvalid},
+		{`{"a":`, `{}`, ``, errAny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.doc+" "+tt.patch, func(t *testing.T) {
+			got, err := Merge([]byte(tt.doc), []byte(tt.patch))
+			checkResult(t, got, err, tt.want, tt.err)
+		})
+	}
+}
+
+// TestApply runs the examples from Appendix A of RFC 6902 followed by
+// other edge cases.
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+		err                    error
+	}{
+		{
+			"add object member",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux"}]`,
+			`{"baz":"qux","foo":"bar"}`, nil,
+		},
+		{
+			"add array element",
+			`{"foo":["bar","baz"]}`,
+			`[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			`{"foo":["bar","qux","baz"]}`, nil,
+		},
+		{
+			"remove object member",
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"remove","path":"/baz"}]`,
+			`{"foo":"bar"}`, nil,
+		},
+		{
+			"remove array element",
+			`{"foo":["bar","qux","baz"]}`,
+			`[{"op":"remove","path":"/foo/1"}]`,
+			`{"foo":["bar","baz"]}`, nil,
+		},
+		{
+			"replace value",
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":"boo"}]`,
+			`{"baz":"boo","foo":"bar"}`, nil,
+		},
+		{
+			"move value",
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, nil,
+		},
+		{
+			"move array element",
+			`{"foo":["all","grass","cows","eat"]}`,
+			`[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`, nil,
+		},
+		{
+			"test value success",
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`, nil,
+		},
+		{
+			"test value error",
+			`{"baz":"qux"}`,
+			`[{"op":"test","path":"/baz","value":"bar"}]`,
+			``, ErrTestFailed,
+		},
+		{
+			"add nested member object",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			`{"foo":"bar","child":{"grandchild":{}}}`, nil,
+		},
+		{
+			"ignore unrecognized elements",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","xyz":123}]`,
+			`{"foo":"bar","baz":"qux"}`, nil,
+		},
+		{
+			"add to nonexistent target",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			``, errAny,
+		},
+		{
+			"escape ordering",
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":10}]`,
+			`{"/":9,"~1":10}`, nil,
+		},
+		{
+			"compare strings and numbers",
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":"10"}]`,
+			``, ErrTestFailed,
+		},
+		{
+			"add array value",
+			`{"foo":["bar"]}`,
+			`[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			`{"foo":["bar",["abc","def"]]}`, nil,
+		},
+		{
+			"replace whole document",
+			`{"foo":"bar"}`,
+			`[{"op":"replace","path":"","value":[1]}]`,
+			`[1]`, nil,
+		},
+		{
+			"copy is deep",
+			`{"a":{"x":1}}`,
+			`[{"op":"copy","from":"/a","path":"/b"},{"op":"replace","path":"/b/x","value":2}]`,
+			`{"a":{"x":1},"b":{"x":2}}`, nil,
+		},
+		{
+			"set null value",
+			`{"foo":"bar"}`,
+			`[{"op":"replace","path":"/foo","value":null}]`,
+			`{"foo":null}`, nil,
+		},
+		{
+			"later operation fails",
+			`{"foo":"bar"}`,
+			`[{"op":"remove","path":"/foo"},{"op":"remove","path":"/foo"}]`,
+			``, errAny,
+		},
+		{"leading zero index", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/01"}]`, ``, errAny},
+		{"index out of range", `{"foo":[1,2]}`, `[{"op":"add","path":"/foo/3","value":3}]`, ``, errAny},
+		{"remove whole document", `{"foo":"bar"}`, `[{"op":"remove","path":""}]`, ``, ErrInvalid},
+		{"move into itself", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, ``, ErrInvalid},
+		{"missing path", `{}`, `[{"op":"add","value":1}]`, ``, ErrInvalid},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, ``, ErrInvalid},
+		{"missing from", `{}`, `[{"op":"copy","path":"/a"}]`, ``, ErrInvalid},
+		{"pointer without slash", `{}`, `[{"op":"add","path":"a","value":1}]`, ``, ErrInvalid},
+		{"unknown op", `{}`, `[{"op":"merge","path":"/a","value":1}]`, ``, ErrInvalid},
+		{"not an array", `{}`, `{"op":"add","path":"/a","value":1}`, ``, ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			checkResult(t, got, err, tt.want, tt.err)
+		})
+	}
+}
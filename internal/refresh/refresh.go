@@ -0,0 +1,157 @@
+package refresh
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"../service"
+)
+
+// Refresher is a background worker that periodically re-fetches the
+// external metadata of movies once it is older than MaxAge. Every refresh
+// is recorded as a job so its outcome can be inspected later.
+type Refresher struct {
+	MovieService    service.MovieService
+	MetadataService service.MetadataService
+	JobService      service.JobService
+
+	// Interval is how often to look for movies with stale metadata.
+	Interval time.Duration
+
+	// MaxAge is how old metadata may get before it is refreshed.
+	MaxAge time.Duration
+
+	// Rate is the minimum delay between requests for metadata.
+	Rate time.Duration
+
+	// MaxRetries is how many times a failed request is retried. The delay
+	// before a retry starts at Backoff and doubles with every attempt.
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+// Run refreshes stale metadata immediately and then every Interval until
+// ctx is cancelled. A refresh in progress is abandoned on cancellation,
+// marking its job as cancelled. Run always returns ctx.Err(). Changes
+// are recorded in the movie history as made by the refresher.
+func (r *Refresher) Run(ctx context.Context) error {
+	ctx = service.NewActorContext(ctx, "refresher")
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh re-enriches every movie whose metadata is older than MaxAge.
+func (r *Refresher) refresh(ctx context.Context) {
+	limiter := time.NewTicker(r.Rate)
+	defer limiter.Stop()
+
+	filter := service.MovieFilter{
+		Limit:          service.MaxLimit,
+		SortBy:         service.SortByID,
+		EnrichedBefore: time.Now().Add(-r.MaxAge),
+	}
+
+	for {
+		movies, next, err := r.MovieService.GetMovies(ctx, filter)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Println("Error:", err)
+			}
+			return
+		}
+
+		for _, movie := range *movies {
+			if err := r.refreshMovie(ctx, limiter.C, movie); err != nil {
+				log.Println("Error:", err)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		if next == nil {
+			return
+		}
+		filter = filter.Next(next)
+	}
+}
+
+// refreshMovie fetches and stores the metadata for a single movie,
+// retrying with backoff on failure. Requests are only sent when a tick
+// is received from limiter.
+func (r *Refresher) refreshMovie(ctx context.Context, limiter <-chan time.Time, movie *service.Movie) error {
+	job := &service.Job{
+		Kind:    service.JobRefreshMetadata,
+		MovieID: movie.ID,
+		Status:  service.JobRunning,
+	}
+
+	id, err := r.JobService.CreateJob(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	var md *service.Metadata
+	backoff := r.Backoff
+	for {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-limiter:
+			job.Attempts++
+			md, err = r.MetadataService.GetMetadata(ctx, movie.ImdbID)
+		}
+
+		// Give up once cancelled, out of retries or if the movie is
+		// unknown, which retrying won't fix.
+		if err == nil || ctx.Err() != nil || job.Attempts > r.MaxRetries ||
+			err == service.ErrMetadataNotFound {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	if err == nil {
+		err = r.MovieService.UpdateMetadata(ctx, movie.ID, md)
+	}
+
+	switch {
+	case err == nil:
+		job.Status = service.JobSucceeded
+	case ctx.Err() != nil:
+		job.Status = service.JobCancelled
+		job.Error = ctx.Err().Error()
+	default:
+		job.Status = service.JobFailed
+		job.Error = err.Error()
+	}
+
+	// The job is updated without ctx so that its final status is still
+	// recorded when the refresh was cancelled.
+	if uerr := r.JobService.UpdateJob(context.Background(), id, job); uerr != nil {
+		return uerr
+	}
+
+	if job.Status == service.JobCancelled {
+		return nil
+	}
+
+	return err
+}
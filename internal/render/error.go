@@ -0,0 +1,101 @@
+package render
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"../service"
+)
+
+// statuses maps service error codes to HTTP status codes.
+var statuses = map[string]int{
+	service.EBadRequest:   http.StatusBadRequest,
+	service.EConflict:     http.StatusConflict,
+	service.EInternal:     http.StatusInternalServerError,
+	service.EForbidden:    http.StatusForbidden,
+	service.EInvalid:      http.StatusUnprocessableEntity,
+	service.ENotFound:     http.StatusNotFound,
+	service.EUnauthorized: http.StatusUnauthorized,
+	service.EPrecondition: http.StatusPreconditionFailed,
+	service.EUnsupported:  http.StatusUnsupportedMediaType,
+	service.EUpstream:     http.StatusBadGateway,
+}
+
+// Problem is a struct containing an RFC 7807 problem details object.
+// Code, Errors and Existing are extension members holding the stable
+// service error code, any per-field validation errors and, for conflicts,
+// the URL of the existing resource.
+type Problem struct {
+	Type     string             `json:"type"`
+	Title    string             `json:"title"`
+	Status   int                `json:"status"`
+	Detail   string             `json:"detail,omitempty"`
+	Instance string             `json:"instance,omitempty"`
+	Code     string             `json:"code"`
+	Errors   service.Validation `json:"errors,omitempty"`
+	Existing string             `json:"existing,omitempty"`
+}
+
+// Status returns the HTTP status code for err based on its service
+// error code.
+func Status(err error) int {
+	if status, ok := statuses[service.ErrorCode(err)]; ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+// NewProblem builds the problem details for err. Only the client safe
+// message of err is included.
+func NewProblem(r *http.Request, err error) *Problem {
+	code := service.ErrorCode(err)
+	status := Status(err)
+
+	return &Problem{
+		Type:     "urn:pmdb:problem:" + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   service.ErrorMessage(err),
+		Instance: r.URL.Path,
+		Code:     code,
+		Errors:   service.ErrorFields(err),
+	}
+}
+
+// Error renders err as a problem+json response and sets the content type
+// and status.
+func Error(w http.ResponseWriter, r *http.Request, err error) error {
+	return ProblemJSON(w, NewProblem(r, err))
+}
+
+// ProblemJSON renders a problem+json response and sets the content type
+// and status.
+func ProblemJSON(w http.ResponseWriter, p *Problem) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+
+	result, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	w.Write(result)
+	return nil
+}
+
+// HTMLError renders err as a HTML error page and sets the content type
+// and status. Pages exist for 404 and 500 responses; other statuses get
+// a plain text response.
+func HTMLError(w http.ResponseWriter, r *http.Request, err error) error {
+	status := Status(err)
+	switch status {
+	case http.StatusNotFound:
+		return HTML(w, status, "error/404.html", nil)
+	case http.StatusInternalServerError:
+		return HTML(w, status, "error/500.html", nil)
+	default:
+		http.Error(w, service.ErrorMessage(err), status)
+		return nil
+	}
+}
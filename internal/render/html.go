@@ -1,18 +1,26 @@
 package render
 
 import (
+	"html/template"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
-	"text/template"
 )
 
 var tpl *template.Template
 
-func init() {
-	tpl = template.Must(findAndParseTemplates("internal/templates/", nil))
+// Load finds and parses the templates in dir, replacing any templates
+// previously loaded. It must be called before HTML.
+func Load(dir string) error {
+	t, err := findAndParseTemplates(dir, nil)
+	if err != nil {
+		return err
+	}
+
+	tpl = t
+	return nil
 }
 
 // HTML renders a simple HTML response and sets the content type and status.
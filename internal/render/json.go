@@ -7,14 +7,21 @@ import (
 
 type data struct {
 	Data interface{} `json:"data"`
+	Meta interface{} `json:"meta,omitempty"`
 }
 
 // JSON renders a simple JSON response and sets the content type and status.
 func JSON(w http.ResponseWriter, status int, v interface{}) error {
+	return JSONMeta(w, status, v, nil)
+}
+
+// JSONMeta renders a JSON response with metadata, such as pagination
+// details, alongside the data and sets the content type and status.
+func JSONMeta(w http.ResponseWriter, status int, v interface{}, meta interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
-	result, err := json.Marshal(data{Data: v})
+	result, err := json.Marshal(data{Data: v, Meta: meta})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on the length of collection fields, in characters.
+const (
+	MaxCollectionNameLength        = 100
+	MaxCollectionDescriptionLength = 1000
+)
+
+// Collection is a struct containing a named group of movies in the
+// library of its owner, such as "Criterion". Names are unique within a
+// library, ignoring case.
+type Collection struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	MovieCount  int       `json:"movieCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Collections is a slice of collection structs, ordered by name.
+type Collections []*Collection
+
+// Validate trims the fields of the collection and checks them, returning
+// an EInvalid error with an entry for every invalid field.
+func (c *Collection) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Description = strings.TrimSpace(c.Description)
+
+	v := Validation{}
+	v.Check(c.Name != "", "name", "is required")
+	v.Check(utf8.RuneCountInString(c.Name) <= MaxCollectionNameLength, "name",
+		fmt.Sprintf("must be at most %d characters", MaxCollectionNameLength))
+	v.Check(utf8.RuneCountInString(c.Description) <= MaxCollectionDescriptionLength, "description",
+		fmt.Sprintf("must be at most %d characters", MaxCollectionDescriptionLength))
+
+	if len(v) == 0 {
+		return nil
+	}
+
+	return &Error{Code: EInvalid, Message: "the collection is invalid", Fields: v}
+}
+
+// ErrCollectionNotFound is returned by a CollectionService for a
+// collection that does not exist or is in another library.
+var ErrCollectionNotFound = &Error{Code: ENotFound, Message: "collection not found"}
+
+// CollectionService contains function signatures for implementing a
+// collection service. Collections belong to the library of the user in
+// the context, like tags, and only hold movies in the same library.
+// Adding a movie twice does nothing; deleting a collection leaves its
+// movies in the library.
+type CollectionService interface {
+	GetCollections(ctx context.Context) (*Collections, error)
+	GetCollection(ctx context.Context, id int64) (*Collection, error)
+	CreateCollection(ctx context.Context, c *Collection) error
+	UpdateCollection(ctx context.Context, id int64, c *Collection) error
+	DeleteCollection(ctx context.Context, id int64) error
+	AddMovie(ctx context.Context, id, movieID int64) error
+	RemoveMovie(ctx context.Context, id, movieID int64) error
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Copy formats.
+const (
+	Format4K      = "4k"
+	FormatBluRay  = "blu-ray"
+	FormatDVD     = "dvd"
+	FormatDigital = "digital"
+	FormatOther   = "other"
+)
+
+// MaxCopyFieldLength is the longest edition, region or location of a
+// copy, in characters.
+const MaxCopyFieldLength = 255
+
+// Copy is a struct containing a copy of a movie owned by the owner of the
+// movie, such as a Blu-ray. Barcode is the UPC or EAN on the case, as
+// digits only, and PriceCents the purchase price in cents. Location is
+// where the copy is kept, such as a shelf.
+type Copy struct {
+	ID          int64     `json:"id"`
+	MovieID     int64     `json:"movieId"`
+	Format      string    `json:"format"`
+	Edition     string    `json:"edition,omitempty"`
+	Region      string    `json:"region,omitempty"`
+	Barcode     string    `json:"barcode,omitempty"`
+	PurchasedOn string    `json:"purchasedOn,omitempty"` // As YYYY-MM-DD.
+	PriceCents  int       `json:"priceCents,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Copies is a slice of copy structs.
+type Copies []*Copy
+
+// Validate trims the text fields of the copy, strips spaces and dashes
+// from its barcode and checks its fields, returning an EInvalid error
+// with an entry for every invalid field.
+func (c *Copy) Validate() error {
+	c.Format = strings.ToLower(strings.TrimSpace(c.Format))
+	c.Edition = strings.TrimSpace(c.Edition)
+	c.Region = strings.TrimSpace(c.Region)
+	c.Barcode = NormalizeBarcode(c.Barcode)
+	c.PurchasedOn = strings.TrimSpace(c.PurchasedOn)
+	c.Location = strings.TrimSpace(c.Location)
+
+	long := fmt.Sprintf("must be at most %d characters", MaxCopyFieldLength)
+
+	v := Validation{}
+	switch c.Format {
+	case Format4K, FormatBluRay, FormatDVD, FormatDigital, FormatOther:
+	default:
+		v.Check(false, "format", "must be one of 4k, blu-ray, dvd, digital or other")
+	}
+	v.Check(utf8.RuneCountInString(c.Edition) <= MaxCopyFieldLength, "edition", long)
+	v.Check(utf8.RuneCountInString(c.Region) <= MaxCopyFieldLength, "region", long)
+	v.Check(c.Barcode == "" || validBarcode(c.Barcode), "barcode", "must be a UPC or EAN of 8 to 14 digits")
+	if c.PurchasedOn != "" {
+		_, err := time.Parse(DateLayout, c.PurchasedOn)
+		v.Check(err == nil, "purchasedOn", "must be a date, such as 2006-01-02")
+	}
+	v.Check(c.PriceCents >= 0, "priceCents", "must not be negative")
+	v.Check(utf8.RuneCountInString(c.Location) <= MaxCopyFieldLength, "location", long)
+
+	if len(v) == 0 {
+		return nil
+	}
+
+	return &Error{Code: EInvalid, Message: "the copy is invalid", Fields: v}
+}
+
+// NormalizeBarcode removes the spaces and dashes barcodes are often
+// written with.
+func NormalizeBarcode(s string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(s))
+}
+
+// validBarcode reports whether s is made of 8 to 14 digits, which covers
+// EAN-8, UPC-A, EAN-13 and GTIN-14.
+func validBarcode(s string) bool {
+	if len(s) < 8 || len(s) > 14 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// InventoryEntry is a struct containing a copy along with the title and
+// IMDb id of the movie it is a copy of.
+type InventoryEntry struct {
+	*Copy
+	Title  string `json:"title"`
+	ImdbID string `json:"imdbId"`
+}
+
+// Inventory is a slice of inventory entry structs.
+type Inventory []*InventoryEntry
+
+// ErrCopyNotFound is returned by a CopyService for a copy that does not
+// exist or is a copy of another movie.
+var ErrCopyNotFound = &Error{Code: ENotFound, Message: "copy not found"}
+
+// CopyService contains function signatures for implementing a copy
+// service. Like a ReviewService, the movie must be visible to the user in
+// the context. GetInventory returns the copies of every movie in that
+// user's library that is not in the trash, only those with the barcode
+// unless it is empty.
+type CopyService interface {
+	GetCopies(ctx context.Context, movieID int64) (*Copies, error)
+	GetCopy(ctx context.Context, movieID, id int64) (*Copy, error)
+	CreateCopy(ctx context.Context, movieID int64, c *Copy) error
+	UpdateCopy(ctx context.Context, movieID, id int64, c *Copy) error
+	DeleteCopy(ctx context.Context, movieID, id int64) error
+	GetInventory(ctx context.Context, barcode string) (*Inventory, error)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes. They are stable and safe to expose to clients.
+const (
+	EBadRequest   = "bad_request"         // The request could not be understood.
+	EConflict     = "conflict"            // The change conflicts with existing data.
+	EInternal     = "internal"            // Something went wrong on our side.
+	EForbidden    = "forbidden"           // The credentials do not allow the request.
+	EInvalid      = "invalid"             // The data failed validation.
+	ENotFound     = "not_found"           // The resource does not exist.
+	EUnauthorized = "unauthorized"        // The request needs valid credentials.
+	EPrecondition = "precondition_failed" // The resource has changed since it was read.
+	EUnsupported  = "unsupported"         // The body format is not supported.
+	EUpstream     = "upstream"            // An external service failed.
+)
+
+// Error is a struct containing a domain error. The code, message and
+// field errors are meant for clients, while the wrapped error holds
+// details, such as a driver error, that are only meant for logs.
+type Error struct {
+	Code    string
+	Message string
+	Fields  Validation
+	Err     error
+}
+
+// Errorf returns an error with the code and a formatted message.
+func Errorf(code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error returns the message followed by the wrapped error, if any.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the code of err if it is, or wraps, an *Error.
+// Any other non-nil error is reported as EInternal.
+func ErrorCode(err error) string {
+	var e *Error
+	if err == nil {
+		return ""
+	} else if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return EInternal
+}
+
+// ErrorMessage returns the client safe message of err if it is, or
+// wraps, an *Error. Any other error gets a generic message so that
+// internal details are never exposed.
+func ErrorMessage(err error) string {
+	var e *Error
+	if err == nil {
+		return ""
+	} else if errors.As(err, &e) {
+		return e.Message
+	}
+
+	return "An internal error has occurred."
+}
+
+// ErrorFields returns the per-field validation errors of err if it is, or
+// wraps, an *Error.
+func ErrorFields(err error) Validation {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+
+	return nil
+}
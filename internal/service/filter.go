@@ -0,0 +1,259 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sort keys that movies can be ordered by.
+const (
+	SortByID        = "id"
+	SortByTitle     = "title"
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+)
+
+// Limits applied to the number of movies returned per page.
+const (
+	DefaultLimit = 25
+	MaxLimit     = 100
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+// or was issued for a different sort order.
+var ErrInvalidCursor = &Error{Code: EBadRequest, Message: "invalid cursor"}
+
+// MovieFilter is a struct containing the options for listing movies. The
+// zero value lists every movie ordered by id.
+type MovieFilter struct {
+	Limit  int     // Maximum number of movies to return, 0 for no limit.
+	After  *Cursor // Position to continue listing from.
+	SortBy string  // One of the SortBy constants.
+	Desc   bool    // Sort in descending order.
+
+	TitlePrefix   string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+
+	// EnrichedBefore matches movies whose metadata was last fetched
+	// before the time. Movies that were never enriched don't match.
+	EnrichedBefore time.Time
+
+	// Tag matches movies with the tag of that name, ignoring case, and
+	// Collection movies in the collection with that id.
+	Tag        string
+	Collection int64
+
+	// Trashed lists the movies in the trash instead of the library.
+	Trashed bool
+}
+
+// Sort returns the sort expression for the filter, e.g. "-title".
+func (f MovieFilter) Sort() string {
+	sortBy := f.SortBy
+	if sortBy == "" {
+		sortBy = SortByID
+	}
+	if f.Desc {
+		return "-" + sortBy
+	}
+
+	return sortBy
+}
+
+// Query encodes the filter as URL query parameters. It is the inverse of
+// ParseMovieFilter.
+func (f MovieFilter) Query() url.Values {
+	q := url.Values{}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if f.After != nil {
+		q.Set("cursor", f.After.String())
+	}
+	if f.SortBy != "" && (f.SortBy != SortByID || f.Desc) {
+		q.Set("sort", f.Sort())
+	}
+	if f.TitlePrefix != "" {
+		q.Set("title_prefix", f.TitlePrefix)
+	}
+	if f.Tag != "" {
+		q.Set("tag", f.Tag)
+	}
+	if f.Collection != 0 {
+		q.Set("collection", strconv.FormatInt(f.Collection, 10))
+	}
+
+	times := map[string]time.Time{
+		"created_after":   f.CreatedAfter,
+		"created_before":  f.CreatedBefore,
+		"updated_after":   f.UpdatedAfter,
+		"updated_before":  f.UpdatedBefore,
+		"enriched_before": f.EnrichedBefore,
+	}
+	for key, t := range times {
+		if !t.IsZero() {
+			q.Set(key, t.Format(time.RFC3339))
+		}
+	}
+
+	return q
+}
+
+// Next returns a copy of the filter that continues from the cursor.
+func (f MovieFilter) Next(c *Cursor) MovieFilter {
+	f.After = c
+	return f
+}
+
+// ParseMovieFilter builds a filter from URL query parameters. The limit
+// defaults to DefaultLimit and is capped at MaxLimit. Sort accepts a sort
+// key optionally prefixed with "-" for descending order, and the time
+// ranges accept RFC 3339 timestamps.
+func ParseMovieFilter(q url.Values) (MovieFilter, error) {
+	f := MovieFilter{Limit: DefaultLimit, SortBy: SortByID}
+
+	limit, err := ParseLimit(q.Get("limit"))
+	if err != nil {
+		return f, err
+	}
+	f.Limit = limit
+
+	if v := q.Get("sort"); v != "" {
+		f.Desc = strings.HasPrefix(v, "-")
+		f.SortBy = strings.TrimPrefix(v, "-")
+		switch f.SortBy {
+		case SortByID, SortByTitle, SortByCreatedAt, SortByUpdatedAt:
+		default:
+			return f, Errorf(EBadRequest, "invalid sort %q", v)
+		}
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := ParseCursor(v)
+		if err != nil {
+			return f, err
+		}
+		if c.SortBy != f.SortBy || c.Desc != f.Desc {
+			return f, ErrInvalidCursor
+		}
+		f.After = c
+	}
+
+	f.TitlePrefix = q.Get("title_prefix")
+	f.Tag = q.Get("tag")
+
+	if v := q.Get("collection"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			return f, Errorf(EBadRequest, "invalid collection %q", v)
+		}
+		f.Collection = id
+	}
+
+	times := map[string]*time.Time{
+		"created_after":   &f.CreatedAfter,
+		"created_before":  &f.CreatedBefore,
+		"updated_after":   &f.UpdatedAfter,
+		"updated_before":  &f.UpdatedBefore,
+		"enriched_before": &f.EnrichedBefore,
+	}
+	for key, t := range times {
+		v := q.Get(key)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, Errorf(EBadRequest, "invalid %s %q", key, v)
+		}
+		*t = parsed
+	}
+
+	return f, nil
+}
+
+// ParseLimit parses a page size query parameter. An empty value defaults
+// to DefaultLimit and larger values are capped at MaxLimit.
+func ParseLimit(v string) (int, error) {
+	if v == "" {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 {
+		return 0, Errorf(EBadRequest, "invalid limit %q", v)
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	return limit, nil
+}
+
+// Cursor marks the position of a movie within a sorted list of movies.
+// It records the sort order it was issued for, the sort value of the
+// movie and its id, which breaks ties between equal sort values.
+type Cursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Value  string `json:"v"`
+	ID     int64  `json:"id"`
+}
+
+// NewCursor returns a cursor positioned at the movie for the filter's
+// sort order.
+func NewCursor(f MovieFilter, m *Movie) *Cursor {
+	c := &Cursor{SortBy: f.SortBy, Desc: f.Desc, ID: m.ID}
+	switch f.SortBy {
+	case SortByTitle:
+		c.Value = m.Title
+	case SortByCreatedAt:
+		c.Value = m.CreatedAt.Format(time.RFC3339Nano)
+	case SortByUpdatedAt:
+		c.Value = m.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		c.SortBy = SortByID
+		c.Value = strconv.FormatInt(m.ID, 10)
+	}
+
+	return c
+}
+
+// Time returns the cursor value as a time for time based sort orders.
+func (c *Cursor) Time() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, c.Value)
+}
+
+// String encodes the cursor as an opaque URL safe token.
+func (c *Cursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCursor decodes a token created by Cursor.String.
+func ParseCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.SortBy == SortByCreatedAt || c.SortBy == SortByUpdatedAt {
+		if _, err := c.Time(); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"encoding/base64"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)
+	movie := &Movie{ID: 42, Title: "Heat", CreatedAt: created, UpdatedAt: created.Add(time.Hour)}
+
+	tests := []struct {
+		name  string
+		f     MovieFilter
+		value string
+	}{
+		{"default", MovieFilter{}, "42"},
+		{"id", MovieFilter{SortBy: SortByID}, "42"},
+		{"title", MovieFilter{SortBy: SortByTitle}, "Heat"},
+		{"title desc", MovieFilter{SortBy: SortByTitle, Desc: true}, "Heat"},
+		{"created_at", MovieFilter{SortBy: SortByCreatedAt}, "2024-05-01T12:30:00.123456789Z"},
+		{"updated_at desc", MovieFilter{SortBy: SortByUpdatedAt, Desc: true}, "2024-05-01T13:30:00.123456789Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCursor(tt.f, movie)
+			if c.Value != tt.value || c.ID != movie.ID {
+				t.Fatalf("NewCursor() = %+v, want value %q and id %d", c, tt.value, movie.ID)
+			}
+
+			got, err := ParseCursor(c.String())
+			if err != nil {
+				t.Fatalf("ParseCursor(%q) error = %v", c.String(), err)
+			}
+			if !reflect.DeepEqual(got, c) {
+				t.Errorf("ParseCursor(%q) = %+v, want %+v", c.String(), got, c)
+			}
+		})
+	}
+}
+
+func TestParseCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"s":"id","v":"1","id":1}`))},
+		{"not json", encode("cursor")},
+		{"wrong json type", encode(`{"s":"id","v":"1","id":"1"}`)},
+		{"bad created_at value", encode(`{"s":"created_at","v":"yesterday","id":1}`)},
+		{"bad updated_at value", encode(`{"s":"updated_at","v":"","id":1}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c, err := ParseCursor(tt.cursor); err != ErrInvalidCursor {
+				t.Errorf("ParseCursor(%q) = %+v, %v, want ErrInvalidCursor", tt.cursor, c, err)
+			}
+		})
+	}
+}
+
+func TestParseMovieFilter(t *testing.T) {
+	after := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	titleCursor := &Cursor{SortBy: SortByTitle, Desc: true, Value: "Heat", ID: 7}
+
+	tests := []struct {
+		name  string
+		query string
+		want  MovieFilter
+	}{
+		{"defaults", "", MovieFilter{Limit: DefaultLimit, SortBy: SortByID}},
+		{"limit", "limit=10", MovieFilter{Limit: 10, SortBy: SortByID}},
+		{"limit capped", "limit=1000", MovieFilter{Limit: MaxLimit, SortBy: SortByID}},
+		{"descending sort", "sort=-title", MovieFilter{Limit: DefaultLimit, SortBy: SortByTitle, Desc: true}},
+		{
+			"cursor",
+			"sort=-title&cursor=" + titleCursor.String(),
+			MovieFilter{Limit: DefaultLimit, SortBy: SortByTitle, Desc: true, After: titleCursor},
+		},
+		{
+			"filters",
+			"title_prefix=Ave&tag=Christmas&collection=3&created_after=2024-01-02T03:04:05Z",
+			MovieFilter{
+				Limit:        DefaultLimit,
+				SortBy:       SortByID,
+				TitlePrefix:  "Ave",
+				Tag:          "Christmas",
+				Collection:   3,
+				CreatedAfter: after,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := ParseMovieFilter(q)
+			if err != nil {
+				t.Fatalf("ParseMovieFilter(%q) error = %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMovieFilter(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+
+			// Query is the inverse of ParseMovieFilter.
+			again, err := ParseMovieFilter(got.Query())
+			if err != nil {
+				t.Fatalf("ParseMovieFilter(%q) error = %v", got.Query().Encode(), err)
+			}
+			if !reflect.DeepEqual(again, got) {
+				t.Errorf("ParseMovieFilter(%q) = %+v, want %+v", got.Query().Encode(), again, got)
+			}
+		})
+	}
+}
+
+func TestParseMovieFilterInvalid(t *testing.T) {
+	idCursor := &Cursor{SortBy: SortByID, Value: "7", ID: 7}
+
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"zero limit", "limit=0", EBadRequest},
+		{"word limit", "limit=ten", EBadRequest},
+		{"unknown sort", "sort=rating", EBadRequest},
+		{"bad cursor", "cursor=nope", EBadRequest},
+		{"cursor for another sort", "sort=title&cursor=" + idCursor.String(), EBadRequest},
+		{"cursor for another direction", "sort=-id&cursor=" + idCursor.String(), EBadRequest},
+		{"bad collection", "collection=abc", EBadRequest},
+		{"zero collection", "collection=0", EBadRequest},
+		{"bad time", "updated_before=2024-01-02", EBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			if _, err := ParseMovieFilter(q); ErrorCode(err) != tt.code {
+				t.Errorf("ParseMovieFilter(%q) error = %v, want code %q", tt.query, err, tt.code)
+			}
+		})
+	}
+}
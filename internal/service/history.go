@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// History actions.
+const (
+	HistoryCreate  = "create"
+	HistoryUpdate  = "update"
+	HistoryEnrich  = "enrich"
+	HistoryDelete  = "delete"
+	HistoryRestore = "restore"
+	HistoryPurge   = "purge"
+	HistoryRevert  = "revert"
+)
+
+// HistoryEntry is a struct containing a recorded change to a movie. Before
+// is nil for a created movie and After is nil for a purged one. Version is
+// the version of the movie after the change.
+type HistoryEntry struct {
+	ID        int64     `json:"id"`
+	MovieID   int64     `json:"movieId"`
+	Version   int64     `json:"version"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Before    *Movie    `json:"before"`
+	After     *Movie    `json:"after"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// OwnerID is the id of the user whose library the movie is in.
+	OwnerID int64 `json:"-"`
+}
+
+// History is a slice of history entry structs, most recent first.
+type History []*HistoryEntry
+
+// ErrVersionNotFound is returned by a MovieService when reverting a movie
+// to a version that is not in its history.
+var ErrVersionNotFound = &Error{Code: ENotFound, Message: "version not found in the movie history"}
+
+// actorKey is the context key for the actor making a change.
+type actorKey struct{}
+
+// AnonymousActor is the actor recorded for changes made without one.
+const AnonymousActor = "anonymous"
+
+// NewActorContext returns a copy of ctx carrying the name of the actor
+// making changes, which is recorded in the movie history.
+func NewActorContext(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by ctx, or AnonymousActor.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+
+	return AnonymousActor
+}
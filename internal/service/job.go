@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// Job kinds.
+const (
+	JobRefreshMetadata = "refresh_metadata"
+)
+
+// Job statuses.
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a struct containing the status of a background job run for
+// a movie.
+type Job struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	MovieID   int64     `json:"movieId"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Jobs is a slice of job structs.
+type Jobs []*Job
+
+// JobService contains function signatures for implementing a job service.
+type JobService interface {
+	GetJobs(ctx context.Context, movieID int64) (*Jobs, error)
+	CreateJob(ctx context.Context, j *Job) (int64, error)
+	UpdateJob(ctx context.Context, id int64, j *Job) error
+}
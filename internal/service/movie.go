@@ -1,24 +1,249 @@
 package service
 
-import "time"
+import (
+	"context"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+)
 
 // Movie is a struct containing information about a movie.
 type Movie struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	ImdbID    string    `json:"imdbId"`
+	ID     int64  `json:"id"`
+	Title  string `json:"title"`
+	ImdbID string `json:"imdbId"`
+	Metadata
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+
+	// DeletedAt is when the movie was moved to the trash, or nil if it
+	// is in the library.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+
+	// Version is incremented on every change. It is exposed to clients
+	// as an ETag rather than in the body.
+	Version int64 `json:"-"`
+
+	// OwnerID is the id of the user whose library the movie is in, or 0
+	// for a movie added before there were any users.
+	OwnerID int64 `json:"-"`
+
+	// AverageRating is the average review score of the movie, on the
+	// configured rating scale, and RatingCount is how many reviews it is
+	// based on. Reviews in other libraries are left out, so scores are
+	// never shared between users.
+	AverageRating float64 `json:"averageRating,omitempty"`
+	RatingCount   int     `json:"ratingCount,omitempty"`
+
+	// Tags holds the names of the tags of the movie, in order. Tags are
+	// changed with a TagService.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Metadata is a struct containing information about a movie fetched
+// from an external source such as OMDb.
+type Metadata struct {
+	Year       int        `json:"year,omitempty"`
+	Runtime    int        `json:"runtime,omitempty"` // In minutes.
+	Genres     []string   `json:"genres,omitempty"`
+	Director   string     `json:"director,omitempty"`
+	Cast       []string   `json:"cast,omitempty"`
+	Plot       string     `json:"plot,omitempty"`
+	PosterURL  string     `json:"posterUrl,omitempty"`
+	EnrichedAt *time.Time `json:"enrichedAt,omitempty"`
 }
 
 // Movies is a slice of movie structs.
 type Movies []*Movie
 
+// SearchResult is a struct containing a movie matching a search query,
+// its relevance and a snippet of the matching text, escaped as HTML, with
+// the matched terms wrapped in <mark> tags.
+type SearchResult struct {
+	*Movie
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchResults is a slice of search result structs, ordered from most
+// to least relevant.
+type SearchResults []*SearchResult
+
+// SearchTerms splits a search query into the terms to match. Only
+// letters and digits are significant; everything else separates terms.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Snippet markers are put around the matched terms of a search snippet by
+// a MovieService before it is passed to MarkSnippet.
+const (
+	SnippetStart = "\x02"
+	SnippetEnd   = "\x03"
+)
+
+// MarkSnippet escapes a search snippet as HTML and replaces the snippet
+// markers around the matched terms with <mark> tags.
+func MarkSnippet(s string) string {
+	return strings.NewReplacer(SnippetStart, "<mark>", SnippetEnd, "</mark>").Replace(html.EscapeString(s))
+}
+
+// MovieUpdate is a struct containing a partial update of a movie. Only
+// the non-nil fields are updated. If Version is non-zero the update only
+// succeeds if the movie is still at that version.
+type MovieUpdate struct {
+	Version int64
+
+	Title     *string
+	ImdbID    *string
+	Year      *int
+	Runtime   *int
+	Genres    *[]string
+	Director  *string
+	Cast      *[]string
+	Plot      *string
+	PosterURL *string
+}
+
+// NewMovieUpdate returns the update that turns movie old into movie new,
+// holding only the fields that differ.
+func NewMovieUpdate(old, new *Movie) *MovieUpdate {
+	u := &MovieUpdate{}
+	if new.Title != old.Title {
+		u.Title = &new.Title
+	}
+	if new.ImdbID != old.ImdbID {
+		u.ImdbID = &new.ImdbID
+	}
+	if new.Year != old.Year {
+		u.Year = &new.Year
+	}
+	if new.Runtime != old.Runtime {
+		u.Runtime = &new.Runtime
+	}
+	if !equalStrings(new.Genres, old.Genres) {
+		u.Genres = &new.Genres
+	}
+	if new.Director != old.Director {
+		u.Director = &new.Director
+	}
+	if !equalStrings(new.Cast, old.Cast) {
+		u.Cast = &new.Cast
+	}
+	if new.Plot != old.Plot {
+		u.Plot = &new.Plot
+	}
+	if new.PosterURL != old.PosterURL {
+		u.PosterURL = &new.PosterURL
+	}
+
+	return u
+}
+
+// Apply sets the fields of the movie that are included in the update.
+func (u *MovieUpdate) Apply(m *Movie) {
+	if u.Title != nil {
+		m.Title = *u.Title
+	}
+	if u.ImdbID != nil {
+		m.ImdbID = *u.ImdbID
+	}
+	if u.Year != nil {
+		m.Year = *u.Year
+	}
+	if u.Runtime != nil {
+		m.Runtime = *u.Runtime
+	}
+	if u.Genres != nil {
+		m.Genres = *u.Genres
+	}
+	if u.Director != nil {
+		m.Director = *u.Director
+	}
+	if u.Cast != nil {
+		m.Cast = *u.Cast
+	}
+	if u.Plot != nil {
+		m.Plot = *u.Plot
+	}
+	if u.PosterURL != nil {
+		m.PosterURL = *u.PosterURL
+	}
+}
+
+// equalStrings reports whether a and b hold the same strings. A nil slice
+// equals an empty one.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
 // MovieService contains function signatures for implementing a movie service.
+// Every method accepts a context so that work is abandoned when the caller
+// goes away.
+//
+// UpdateMovie, UpsertMovie, PatchMovie and DeleteMovie take the version of
+// the movie the change is based on, as Movie.Version, MovieUpdate.Version or
+// an argument, and fail with ErrMovieChanged if it is out of date. A zero
+// version skips the check.
+//
+// DeleteMovie moves a movie to the trash, where it is left out of GetMovies
+// and SearchMovies until it is restored or purged for good. Deleting a
+// movie already in the trash fails with ENotFound. UpsertMovie restores a
+// movie in the trash.
+//
+// Every change is recorded in the movie history along with the actor from
+// the context. RevertMovie sets the fields of a movie back to how they were
+// at a version in its history, as a new change.
+//
+// Movies belong to the library of the user who added them. When the
+// context carries a user, see NewUserContext, every method only sees and
+// changes that user's movies, and movies of other users are reported as
+// not found. IMDb ids are unique within a library. Without a user, as for
+// background work, every library is visible.
 type MovieService interface {
-	GetMovies() (*Movies, error)
-	GetMovie(id int64) (*Movie, error)
-	CreateMovie(m *Movie) error
-	UpdateMovie(id int64, m *Movie) error
-	DeleteMovie(id int64) error
+	GetMovies(ctx context.Context, f MovieFilter) (*Movies, *Cursor, error)
+	GetMovie(ctx context.Context, id int64) (*Movie, error)
+	GetMovieByImdbID(ctx context.Context, imdbID string) (*Movie, error)
+	SearchMovies(ctx context.Context, query string, limit int) (*SearchResults, error)
+	CreateMovie(ctx context.Context, m *Movie) (int64, error)
+	UpdateMovie(ctx context.Context, id int64, m *Movie) error
+	UpsertMovie(ctx context.Context, m *Movie) (id int64, created bool, err error)
+	PatchMovie(ctx context.Context, id int64, u *MovieUpdate) error
+	UpdateMetadata(ctx context.Context, id int64, md *Metadata) error
+	DeleteMovie(ctx context.Context, id int64, version int64) error
+	RestoreMovie(ctx context.Context, id int64) error
+	PurgeMovie(ctx context.Context, id int64) error
+	EmptyTrash(ctx context.Context) (int64, error)
+	GetHistory(ctx context.Context, id int64) (*History, error)
+	RevertMovie(ctx context.Context, id int64, version int64) error
+}
+
+// ErrNotInTrash is returned by a MovieService when purging a movie that
+// is not in the trash.
+var ErrNotInTrash = &Error{Code: ENotFound, Message: "movie not found in the trash"}
+
+// ErrMovieChanged is returned by a MovieService when a movie is written
+// with a version other than its current one.
+var ErrMovieChanged = &Error{Code: EPrecondition, Message: "the movie has changed since it was read"}
+
+// ErrMetadataNotFound is returned by a MetadataService when the external
+// source has no metadata for a movie.
+var ErrMetadataNotFound = &Error{Code: EInvalid, Message: "no metadata found for the movie"}
+
+// MetadataService contains function signatures for implementing a service
+// that fetches movie metadata from an external source.
+type MetadataService interface {
+	GetMetadata(ctx context.Context, imdbID string) (*Metadata, error)
 }
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultRatingScale is the highest score a review can give unless the
+// scale is configured otherwise. Scores start at 1.
+const DefaultRatingScale = 10
+
+// MaxReviewLength is the longest review text, in characters.
+const MaxReviewLength = 10000
+
+// DateLayout is the layout of calendar dates, such as the day a movie was
+// watched.
+const DateLayout = "2006-01-02"
+
+// Review is a struct containing the owner's rating and review of a movie
+// in their library. Scale is the rating scale in use when the review was
+// written.
+type Review struct {
+	MovieID   int64     `json:"movieId"`
+	Score     int       `json:"score"`
+	Scale     int       `json:"scale"`
+	Text      string    `json:"text,omitempty"`
+	WatchedOn string    `json:"watchedOn,omitempty"` // As YYYY-MM-DD.
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Validate trims the text of the review and checks its fields against a
+// rating scale, returning an EInvalid error with an entry for every
+// invalid field.
+func (r *Review) Validate(scale int) error {
+	r.Text = strings.TrimSpace(r.Text)
+	r.WatchedOn = strings.TrimSpace(r.WatchedOn)
+
+	v := Validation{}
+	v.Check(r.Score >= 1 && r.Score <= scale, "score",
+		fmt.Sprintf("must be between 1 and %d", scale))
+	v.Check(utf8.RuneCountInString(r.Text) <= MaxReviewLength, "text",
+		fmt.Sprintf("must be at most %d characters", MaxReviewLength))
+	if r.WatchedOn != "" {
+		_, err := time.Parse(DateLayout, r.WatchedOn)
+		v.Check(err == nil, "watchedOn", "must be a date, such as 2006-01-02")
+	}
+
+	if len(v) == 0 {
+		return nil
+	}
+
+	return &Error{Code: EInvalid, Message: "the review is invalid", Fields: v}
+}
+
+// ErrReviewNotFound is returned by a ReviewService for a movie that has
+// not been reviewed.
+var ErrReviewNotFound = &Error{Code: ENotFound, Message: "review not found"}
+
+// ReviewService contains function signatures for implementing a review
+// service. A movie has at most one review, written by its owner, and the
+// movie must be visible to the user in the context as for a MovieService.
+// PutReview creates or replaces the review and reports whether it was
+// created.
+type ReviewService interface {
+	GetReview(ctx context.Context, movieID int64) (*Review, error)
+	PutReview(ctx context.Context, movieID int64, r *Review) (bool, error)
+	DeleteReview(ctx context.Context, movieID int64) error
+}
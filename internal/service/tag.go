@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTagNameLength is the longest tag name, in characters.
+const MaxTagNameLength = 50
+
+// Tag is a struct containing a label that movies in the library of its
+// owner can be given, such as "Christmas". Names are unique within a
+// library, ignoring case.
+type Tag struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	MovieCount int       `json:"movieCount"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Tags is a slice of tag structs, ordered by name.
+type Tags []*Tag
+
+// Validate trims the name of the tag and checks it, returning an EInvalid
+// error if it is missing, too long or holds control characters.
+func (t *Tag) Validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+
+	v := Validation{}
+	v.Check(t.Name != "", "name", "is required")
+	v.Check(utf8.RuneCountInString(t.Name) <= MaxTagNameLength, "name",
+		fmt.Sprintf("must be at most %d characters", MaxTagNameLength))
+	v.Check(strings.IndexFunc(t.Name, unicode.IsControl) < 0, "name",
+		"must not contain control characters")
+
+	if len(v) == 0 {
+		return nil
+	}
+
+	return &Error{Code: EInvalid, Message: "the tag is invalid", Fields: v}
+}
+
+// ErrTagNotFound is returned by a TagService for a tag that does not
+// exist or is in another library.
+var ErrTagNotFound = &Error{Code: ENotFound, Message: "tag not found"}
+
+// TagService contains function signatures for implementing a tag service.
+// Tags belong to the library of the user in the context, like movies, and
+// only movies in the same library can be tagged. Tagging a movie twice
+// does nothing; deleting a tag removes it from its movies.
+type TagService interface {
+	GetTags(ctx context.Context) (*Tags, error)
+	GetTag(ctx context.Context, id int64) (*Tag, error)
+	CreateTag(ctx context.Context, t *Tag) error
+	UpdateTag(ctx context.Context, id int64, t *Tag) error
+	DeleteTag(ctx context.Context, id int64) error
+	TagMovie(ctx context.Context, id, movieID int64) error
+	UntagMovie(ctx context.Context, id, movieID int64) error
+}
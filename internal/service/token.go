@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Token scopes. A write token can also read.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// TokenPrefix starts every personal access token, so that leaked tokens
+// are easy to recognise.
+const TokenPrefix = "pmdb_"
+
+// MaxTokenNameLength is the longest name, in characters, a token can have.
+const MaxTokenNameLength = 100
+
+// Token is a struct containing a personal access token for the API. The
+// secret itself is only known when the token is created; the store keeps
+// a hash and the first characters, as Prefix, to tell tokens apart.
+type Token struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"userId"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Tokens is a slice of token structs.
+type Tokens []*Token
+
+// Active reports whether the token can still be used at the given time.
+func (t *Token) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+// Allows reports whether the token's scope grants scope.
+func (t *Token) Allows(scope string) bool {
+	return t.Scope == scope || t.Scope == ScopeWrite
+}
+
+// Validate trims the name of a new token and checks its fields, returning
+// an EInvalid error with an entry for every invalid field.
+func (t *Token) Validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+
+	v := Validation{}
+	v.Check(t.Name != "", "name", "is required")
+	v.Check(utf8.RuneCountInString(t.Name) <= MaxTokenNameLength, "name",
+		fmt.Sprintf("must be at most %d characters", MaxTokenNameLength))
+	v.Check(t.Scope == ScopeRead || t.Scope == ScopeWrite, "scope",
+		"must be read or write")
+	v.Check(t.ExpiresAt == nil || t.ExpiresAt.After(time.Now()), "expiresAt",
+		"must be in the future")
+
+	if len(v) == 0 {
+		return nil
+	}
+
+	return &Error{Code: EInvalid, Message: "the token is invalid", Fields: v}
+}
+
+// ErrInvalidToken is returned by a TokenService for an unknown, expired
+// or revoked token.
+var ErrInvalidToken = &Error{Code: EUnauthorized, Message: "invalid, expired or revoked token"}
+
+// TokenService contains function signatures for implementing a token
+// service. Tokens belong to a user and act on their behalf. CreateToken
+// fills in the ID, Prefix and CreatedAt of the token and returns the
+// secret, which cannot be retrieved again.
+type TokenService interface {
+	GetTokens(ctx context.Context, userID int64) (*Tokens, error)
+	CreateToken(ctx context.Context, t *Token) (string, error)
+	RevokeToken(ctx context.Context, userID, id int64) error
+	AuthenticateToken(ctx context.Context, secret string) (*User, *Token, error)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Limits on user credentials. Bcrypt ignores anything past 72 bytes, so
+// longer passwords are rejected rather than silently truncated.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// usernamePattern matches a username of 1 to 64 letters, digits, dots,
+// dashes and underscores.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// User is a struct containing an account that can sign in to the site.
+type User struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Session is a struct containing a signed in browser session. The token
+// is only known when the session is created; the store keeps a hash.
+type Session struct {
+	Token     string    `json:"-"`
+	UserID    int64     `json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ErrInvalidCredentials is returned by a UserService when a username and
+// password do not match an account. It does not say which one was wrong.
+var ErrInvalidCredentials = &Error{Code: EUnauthorized, Message: "invalid username or password"}
+
+// ErrSessionNotFound is returned by a UserService for an unknown or
+// expired session token.
+var ErrSessionNotFound = &Error{Code: EUnauthorized, Message: "session expired, please sign in again"}
+
+// ValidateCredentials checks a username and password for a new account, or
+// a new password, and returns an EInvalid error with an entry for every
+// invalid field.
+func ValidateCredentials(username, password string) error {
+	v := Validation{}
+
+	v.Check(username != "", "username", "is required")
+	v.Check(usernamePattern.MatchString(username), "username",
+		"must be at most 64 letters, digits, dots, dashes or underscores")
+
+	v.Check(len(password) >= MinPasswordLength, "password",
+		fmt.Sprintf("must be at least %d characters", MinPasswordLength))
+	v.Check(len(password) <= MaxPasswordLength, "password",
+		fmt.Sprintf("must be at most %d bytes", MaxPasswordLength))
+
+	if len(v) == 0 {
+		return nil
+	}
+
+	return &Error{Code: EInvalid, Message: "the user is invalid", Fields: v}
+}
+
+// UserService contains function signatures for implementing a user
+// service. Passwords are passed in plain text and only ever stored
+// hashed. Usernames are unique regardless of case.
+type UserService interface {
+	GetUser(ctx context.Context, id int64) (*User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	CreateUser(ctx context.Context, username, password string) (int64, error)
+	SetPassword(ctx context.Context, id int64, password string) error
+	Authenticate(ctx context.Context, username, password string) (*User, error)
+
+	CreateSession(ctx context.Context, userID int64, maxAge time.Duration) (*Session, error)
+	GetSessionUser(ctx context.Context, token string) (*User, error)
+	DeleteSession(ctx context.Context, token string) error
+}
+
+// userKey is the context key for the signed in user.
+type userKey struct{}
+
+// NewUserContext returns a copy of ctx carrying the signed in user. The
+// user is also recorded as the actor of any changes.
+func NewUserContext(ctx context.Context, user *User) context.Context {
+	ctx = NewActorContext(ctx, user.Username)
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the signed in user carried by ctx, or nil.
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userKey{}).(*User)
+	return user
+}
+
+// UserIDFromContext returns the id of the signed in user carried by ctx,
+// or 0 if there is none.
+func UserIDFromContext(ctx context.Context) int64 {
+	if user := UserFromContext(ctx); user != nil {
+		return user.ID
+	}
+
+	return 0
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTitleLength is the longest title, in characters, a movie can have.
+const MaxTitleLength = 200
+
+// imdbIDPattern matches an IMDb title id, such as "tt0111161".
+var imdbIDPattern = regexp.MustCompile(`^tt\d{7,}$`)
+
+// Validation collects per-field validation errors, keyed by the JSON name
+// of the field. Only the first error for each field is kept.
+type Validation map[string]string
+
+// Check records message for field if ok is false.
+func (v Validation) Check(ok bool, field, message string) {
+	if _, exists := v[field]; !ok && !exists {
+		v[field] = message
+	}
+}
+
+// Err returns an EInvalid error holding the field errors, or nil if
+// there are none.
+func (v Validation) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	return &Error{Code: EInvalid, Message: "the movie is invalid", Fields: v}
+}
+
+// Normalize trims the whitespace surrounding the user entered fields of
+// the movie.
+func (m *Movie) Normalize() {
+	m.Title = strings.TrimSpace(m.Title)
+	m.ImdbID = strings.TrimSpace(m.ImdbID)
+}
+
+// Validate checks the fields of the movie and returns an EInvalid error
+// with an entry for every invalid field.
+func (m *Movie) Validate() error {
+	v := Validation{}
+
+	v.Check(m.Title != "", "title", "is required")
+	v.Check(utf8.RuneCountInString(m.Title) <= MaxTitleLength, "title",
+		fmt.Sprintf("must be at most %d characters", MaxTitleLength))
+
+	v.Check(m.ImdbID != "", "imdbId", "is required")
+	v.Check(imdbIDPattern.MatchString(m.ImdbID), "imdbId",
+		"must be an IMDb title id, such as tt0111161")
+
+	m.Metadata.validate(v)
+
+	return v.Err()
+}
+
+// validate checks the metadata fields, which are mostly set from OMDb but
+// can be entered by hand.
+func (md *Metadata) validate(v Validation) {
+	v.Check(md.Year == 0 || md.Year >= 1870 && md.Year <= 9999, "year",
+		"must be a four digit year")
+	v.Check(md.Runtime >= 0, "runtime", "must not be negative")
+
+	if md.PosterURL != "" {
+		u, err := url.Parse(md.PosterURL)
+		v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"posterUrl", "must be an http or https URL")
+	}
+}
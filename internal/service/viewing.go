@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxViewingPlaceLength is the longest location or medium of a viewing,
+// in characters.
+const MaxViewingPlaceLength = 100
+
+// MaxViewingNotesLength is the longest notes of a viewing, in characters.
+const MaxViewingNotesLength = 10000
+
+// Viewing is a struct containing a single viewing of a movie, logged in
+// the diary of the movie's owner. Location is where it was watched, such
+// as a cinema, and Medium how, such as a streaming service or a format.
+type Viewing struct {
+	ID        int64     `json:"id"`
+	MovieID   int64     `json:"movieId"`
+	WatchedOn string    `json:"watchedOn"` // As YYYY-MM-DD.
+	Location  string    `json:"location,omitempty"`
+	Medium    string    `json:"medium,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Viewings is a slice of viewing structs, most recent first.
+type Viewings []*Viewing
+
+// Validate trims the text fields of the viewing and checks them,
+// returning an EInvalid error with an entry for every invalid field.
+func (v *Viewing) Validate() error {
+	v.WatchedOn = strings.TrimSpace(v.WatchedOn)
+	v.Location = strings.TrimSpace(v.Location)
+	v.Medium = strings.TrimSpace(v.Medium)
+	v.Notes = strings.TrimSpace(v.Notes)
+
+	_, err := time.Parse(DateLayout, v.WatchedOn)
+	place := fmt.Sprintf("must be at most %d characters", MaxViewingPlaceLength)
+
+	val := Validation{}
+	val.Check(err == nil, "watchedOn", "must be a date, such as 2006-01-02")
+	val.Check(utf8.RuneCountInString(v.Location) <= MaxViewingPlaceLength, "location", place)
+	val.Check(utf8.RuneCountInString(v.Medium) <= MaxViewingPlaceLength, "medium", place)
+	val.Check(utf8.RuneCountInString(v.Notes) <= MaxViewingNotesLength, "notes",
+		fmt.Sprintf("must be at most %d characters", MaxViewingNotesLength))
+
+	if len(val) == 0 {
+		return nil
+	}
+
+	return &Error{Code: EInvalid, Message: "the viewing is invalid", Fields: val}
+}
+
+// DiaryEntry is a struct containing a viewing along with the title and
+// IMDb id of the movie watched.
+type DiaryEntry struct {
+	*Viewing
+	Title  string `json:"title"`
+	ImdbID string `json:"imdbId"`
+}
+
+// Diary is a slice of diary entry structs, most recent first.
+type Diary []*DiaryEntry
+
+// ParseYear parses the year of a diary from a query parameter. An empty
+// value means the current year.
+func ParseYear(v string) (int, error) {
+	if v == "" {
+		return time.Now().Year(), nil
+	}
+
+	year, err := strconv.Atoi(v)
+	if err != nil || year < 1 || year > 9999 {
+		return 0, Errorf(EBadRequest, "invalid year %q", v)
+	}
+
+	return year, nil
+}
+
+// ViewingService contains function signatures for implementing a viewing
+// service. Like a ReviewService, the movie must be visible to the user in
+// the context, and the diary only holds viewings of that user's movies
+// that are not in the trash. CreateViewing logs a viewing today if it has
+// no date.
+type ViewingService interface {
+	GetViewings(ctx context.Context, movieID int64) (*Viewings, error)
+	CreateViewing(ctx context.Context, movieID int64, v *Viewing) error
+	GetDiary(ctx context.Context, year int) (*Diary, error)
+}
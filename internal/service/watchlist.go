@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// Watchlist statuses.
+const (
+	StatusWantToWatch = "want-to-watch"
+	StatusWatching    = "watching"
+	StatusWatched     = "watched"
+	StatusAbandoned   = "abandoned"
+)
+
+// validStatus reports whether s is one of the watchlist statuses.
+func validStatus(s string) bool {
+	switch s {
+	case StatusWantToWatch, StatusWatching, StatusWatched, StatusAbandoned:
+		return true
+	}
+
+	return false
+}
+
+// WatchlistEntry is a struct containing a movie on the watchlist of its
+// owner, along with the title and IMDb id of the movie. The watchlist is
+// ordered by Position, lowest first.
+type WatchlistEntry struct {
+	MovieID   int64     `json:"movieId"`
+	Title     string    `json:"title"`
+	ImdbID    string    `json:"imdbId"`
+	Status    string    `json:"status"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Watchlist is a slice of watchlist entry structs, in watchlist order.
+type Watchlist []*WatchlistEntry
+
+// Validate returns an EInvalid error if the status of the entry is not
+// one of the watchlist statuses.
+func (e *WatchlistEntry) Validate() error {
+	v := Validation{}
+	v.Check(validStatus(e.Status), "status",
+		"must be one of want-to-watch, watching, watched or abandoned")
+
+	if len(v) == 0 {
+		return nil
+	}
+
+	return &Error{Code: EInvalid, Message: "the watchlist entry is invalid", Fields: v}
+}
+
+// ParseStatus parses the watchlist status filter from a query parameter.
+// An empty value means every status.
+func ParseStatus(v string) (string, error) {
+	if v != "" && !validStatus(v) {
+		return "", Errorf(EBadRequest, "invalid status %q", v)
+	}
+
+	return v, nil
+}
+
+// ErrNotOnWatchlist is returned by a WatchlistService for a movie that is
+// not on the watchlist.
+var ErrNotOnWatchlist = &Error{Code: ENotFound, Message: "movie not found on the watchlist"}
+
+// WatchlistService contains function signatures for implementing a
+// watchlist service. Like a ReviewService, the movie must be visible to
+// the user in the context, and the watchlist only holds that user's
+// movies that are not in the trash.
+//
+// PutWatchlistEntry sets the status of a movie, adding it to the end of
+// the watchlist if it is not on it, and reports whether it was added.
+// ReorderWatchlist moves the given movies to the top of the watchlist in
+// the given order, keeping the order of the rest. MarkWatched sets the
+// status of a movie to watched and logs the viewing, as a single change.
+type WatchlistService interface {
+	GetWatchlist(ctx context.Context, status string) (*Watchlist, error)
+	GetWatchlistEntry(ctx context.Context, movieID int64) (*WatchlistEntry, error)
+	PutWatchlistEntry(ctx context.Context, movieID int64, e *WatchlistEntry) (bool, error)
+	DeleteWatchlistEntry(ctx context.Context, movieID int64) error
+	ReorderWatchlist(ctx context.Context, movieIDs []int64) (*Watchlist, error)
+	MarkWatched(ctx context.Context, movieID int64, v *Viewing) error
+}
@@ -0,0 +1,268 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"../service"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Ensure CollectionService implements service.CollectionService.
+var _ service.CollectionService = &CollectionService{}
+
+// CollectionService represents a SQLite implementation of a
+// CollectionService.
+type CollectionService struct {
+	DB *sql.DB
+	Timeout
+}
+
+// collectionColumns lists the columns read by scanCollection, in order.
+// Movies in the trash are left out of the movie count.
+const collectionColumns = `
+	collections.id, collections.name, collections.description,
+	collections.created_at, collections.updated_at,
+	(SELECT COUNT(*)
+		FROM collection_movies JOIN movies ON movies.id = collection_movies.movie_id
+		WHERE collection_movies.collection_id = collections.id AND movies.deleted_at IS NULL)`
+
+// GetCollections returns the collections from the database, ordered by
+// name.
+func (s *CollectionService) GetCollections(ctx context.Context) (*service.Collections, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT `+collectionColumns+`
+		FROM collections
+		WHERE $1 IN (0, owner_id)
+		ORDER BY name, id;
+	`, service.UserIDFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := service.Collections{}
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &collections, nil
+}
+
+// GetCollection returns a single collection from the database.
+func (s *CollectionService) GetCollection(ctx context.Context, id int64) (*service.Collection, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	row := s.DB.QueryRowContext(ctx, `
+		SELECT `+collectionColumns+`
+		FROM collections
+		WHERE id = $1 AND $2 IN (0, owner_id);
+	`, id, service.UserIDFromContext(ctx))
+
+	collection, err := scanCollection(row)
+	if err != nil {
+		return nil, collectionError(err)
+	}
+
+	return collection, nil
+}
+
+// CreateCollection validates the collection and adds it to the library
+// of the user in ctx.
+func (s *CollectionService) CreateCollection(ctx context.Context, c *service.Collection) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	c.MovieCount = 0
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	res, err := s.DB.ExecContext(ctx, `
+		INSERT INTO collections (owner_id, name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4);
+	`, service.UserIDFromContext(ctx), c.Name, c.Description, c.CreatedAt)
+	if err != nil {
+		return collectionError(err)
+	}
+
+	c.ID, err = res.LastInsertId()
+
+	return err
+}
+
+// UpdateCollection validates the collection and sets the name and
+// description of the collection with the given id to its own. The
+// collection is filled in from the database.
+func (s *CollectionService) UpdateCollection(ctx context.Context, id int64, c *service.Collection) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE collections
+		SET name = $1, description = $2, updated_at = $3
+		WHERE id = $4 AND $5 IN (0, owner_id);
+	`, c.Name, c.Description, time.Now(), id, service.UserIDFromContext(ctx))
+	if err != nil {
+		return collectionError(err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.ErrCollectionNotFound
+	}
+
+	current, err := s.GetCollection(ctx, id)
+	if err != nil {
+		return err
+	}
+	*c = *current
+
+	return nil
+}
+
+// DeleteCollection removes a collection from the database. Its movies
+// stay in the library.
+func (s *CollectionService) DeleteCollection(ctx context.Context, id int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	res, err := dbTx.ExecContext(ctx, `
+		DELETE FROM collections
+		WHERE id = $1 AND $2 IN (0, owner_id);
+	`, id, service.UserIDFromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.ErrCollectionNotFound
+	}
+
+	if _, err := dbTx.ExecContext(ctx, `DELETE FROM collection_movies WHERE collection_id = $1;`, id); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+// AddMovie adds a movie to the collection with the given id in the
+// database.
+func (s *CollectionService) AddMovie(ctx context.Context, id, movieID int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMember(ctx, dbTx, "collections", id, movieID); err != nil {
+		return collectionError(err)
+	}
+
+	if _, err := dbTx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO collection_movies (collection_id, movie_id, created_at)
+		VALUES ($1, $2, $3);
+	`, id, movieID, time.Now()); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+// RemoveMovie removes a movie from the collection with the given id in
+// the database.
+func (s *CollectionService) RemoveMovie(ctx context.Context, id, movieID int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMember(ctx, dbTx, "collections", id, movieID); err != nil {
+		return collectionError(err)
+	}
+
+	res, err := dbTx.ExecContext(ctx, `
+		DELETE FROM collection_movies
+		WHERE collection_id = $1 AND movie_id = $2;
+	`, id, movieID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.Errorf(service.ENotFound, "movie not found in the collection")
+	}
+
+	return dbTx.Commit()
+}
+
+// collectionError returns a service error for errors caused by a missing
+// collection or a collection name already in use, and err otherwise.
+func collectionError(err error) error {
+	if err == sql.ErrNoRows {
+		return service.ErrCollectionNotFound
+	}
+
+	var e sqlite3.Error
+	if errors.As(err, &e) && e.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return &service.Error{
+			Code:    service.EConflict,
+			Message: "a collection with that name already exists",
+			Fields:  service.Validation{"name": "is already taken"},
+			Err:     err,
+		}
+	}
+
+	return err
+}
+
+// scanCollection scans a row selected with collectionColumns into a
+// collection.
+func scanCollection(row scanner) (*service.Collection, error) {
+	var c service.Collection
+	if err := row.Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt,
+		&c.UpdatedAt, &c.MovieCount); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
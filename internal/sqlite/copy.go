@@ -0,0 +1,272 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"../service"
+)
+
+// Ensure CopyService implements service.CopyService.
+var _ service.CopyService = &CopyService{}
+
+// CopyService represents a SQLite implementation of a CopyService.
+type CopyService struct {
+	DB *sql.DB
+	Timeout
+}
+
+// copyColumns lists the columns read by scanCopy, in order.
+const copyColumns = `
+	copies.id, movie_id, format, edition, region, barcode, purchased_on,
+	price_cents, location, copies.created_at, copies.updated_at`
+
+// GetCopies returns the copies of a movie from the database, oldest
+// first.
+func (s *CopyService) GetCopies(ctx context.Context, movieID int64) (*service.Copies, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return nil, err
+	}
+
+	rows, err := dbTx.QueryContext(ctx, `
+		SELECT `+copyColumns+`
+		FROM copies
+		WHERE movie_id = $1
+		ORDER BY id;
+	`, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	copies := service.Copies{}
+	for rows.Next() {
+		c, err := scanCopy(rows)
+		if err != nil {
+			return nil, err
+		}
+		copies = append(copies, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &copies, nil
+}
+
+// GetCopy returns a single copy of a movie from the database.
+func (s *CopyService) GetCopy(ctx context.Context, movieID, id int64) (*service.Copy, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return nil, err
+	}
+
+	return selectCopy(ctx, dbTx, movieID, id)
+}
+
+// CreateCopy validates the copy and adds it to the database.
+func (s *CopyService) CreateCopy(ctx context.Context, movieID int64, c *service.Copy) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return err
+	}
+
+	c.MovieID = movieID
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+
+	res, err := dbTx.ExecContext(ctx, `
+		INSERT INTO copies (movie_id, format, edition, region, barcode,
+			purchased_on, price_cents, location, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9);
+	`, c.MovieID, c.Format, c.Edition, c.Region, c.Barcode, c.PurchasedOn,
+		c.PriceCents, c.Location, c.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if c.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+// UpdateCopy validates the copy and replaces the fields of the copy of a
+// movie with the given id with its own. The copy is filled in from the
+// database.
+func (s *CopyService) UpdateCopy(ctx context.Context, movieID, id int64, c *service.Copy) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return err
+	}
+
+	res, err := dbTx.ExecContext(ctx, `
+		UPDATE copies
+		SET format = $1, edition = $2, region = $3, barcode = $4,
+			purchased_on = $5, price_cents = $6, location = $7, updated_at = $8
+		WHERE id = $9 AND movie_id = $10;
+	`, c.Format, c.Edition, c.Region, c.Barcode, c.PurchasedOn, c.PriceCents,
+		c.Location, time.Now(), id, movieID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.ErrCopyNotFound
+	}
+
+	current, err := selectCopy(ctx, dbTx, movieID, id)
+	if err != nil {
+		return err
+	}
+	*c = *current
+
+	return dbTx.Commit()
+}
+
+// DeleteCopy removes a copy of a movie from the database.
+func (s *CopyService) DeleteCopy(ctx context.Context, movieID, id int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return err
+	}
+
+	res, err := dbTx.ExecContext(ctx, `
+		DELETE FROM copies
+		WHERE id = $1 AND movie_id = $2;
+	`, id, movieID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.ErrCopyNotFound
+	}
+
+	return dbTx.Commit()
+}
+
+// GetInventory returns the copies in the library from the database,
+// ordered by movie title, only holding the copies with the barcode unless
+// it is empty.
+func (s *CopyService) GetInventory(ctx context.Context, barcode string) (*service.Inventory, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT `+copyColumns+`, movies.title, movies.imdb_id
+		FROM copies
+		JOIN movies ON movies.id = copies.movie_id
+		WHERE movies.deleted_at IS NULL AND $1 IN (0, movies.owner_id)
+			AND $2 IN ('', barcode)
+		ORDER BY movies.title COLLATE NOCASE, copies.id;
+	`, service.UserIDFromContext(ctx), service.NormalizeBarcode(barcode))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inventory := service.Inventory{}
+	for rows.Next() {
+		var entry service.InventoryEntry
+		entry.Copy, err = scanCopy(rows, &entry.Title, &entry.ImdbID)
+		if err != nil {
+			return nil, err
+		}
+		inventory = append(inventory, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &inventory, nil
+}
+
+// selectCopy returns the copy of the movie with the given ids, or
+// service.ErrCopyNotFound.
+func selectCopy(ctx context.Context, dbTx *sql.Tx, movieID, id int64) (*service.Copy, error) {
+	row := dbTx.QueryRowContext(ctx, `
+		SELECT `+copyColumns+`
+		FROM copies
+		WHERE id = $1 AND movie_id = $2;
+	`, id, movieID)
+
+	c, err := scanCopy(row)
+	if err == sql.ErrNoRows {
+		return nil, service.ErrCopyNotFound
+	}
+
+	return c, err
+}
+
+// scanCopy scans a row selected with copyColumns into a copy. Any extra
+// destinations are scanned from the columns that follow.
+func scanCopy(row scanner, extra ...interface{}) (*service.Copy, error) {
+	var c service.Copy
+
+	dest := []interface{}{&c.ID, &c.MovieID, &c.Format, &c.Edition,
+		&c.Region, &c.Barcode, &c.PurchasedOn, &c.PriceCents, &c.Location,
+		&c.CreatedAt, &c.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
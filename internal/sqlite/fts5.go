@@ -0,0 +1,10 @@
+//go:build !sqlite_fts5
+// +build !sqlite_fts5
+
+package sqlite
+
+// The search migrations need SQLite with FTS5, which go-sqlite3 only
+// compiles in with the sqlite_fts5 build tag. Without the tag the build
+// fails here, naming the missing tag, instead of the server failing to
+// migrate the database when it starts.
+var _ = pmdb_requires_build_tag_sqlite_fts5
@@ -0,0 +1,208 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"../service"
+)
+
+// change runs fn in a transaction and records its effect on the movie with
+// the given id in the movie history. For a new movie id is 0 and fn
+// returns the id of the created movie. Nothing is recorded if fn leaves
+// the movie unchanged, and nothing is written at all if fn fails.
+func (s *MovieService) change(ctx context.Context, action string, id int64, fn func(dbTx *sql.Tx) (int64, error)) (int64, error) {
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer dbTx.Rollback()
+
+	if id, err = changeTx(ctx, dbTx, action, id, fn); err != nil {
+		return 0, err
+	}
+
+	return id, dbTx.Commit()
+}
+
+// changeTx is change for callers that need to read in the same
+// transaction before deciding what to change. The caller commits dbTx.
+func changeTx(ctx context.Context, dbTx *sql.Tx, action string, id int64, fn func(dbTx *sql.Tx) (int64, error)) (int64, error) {
+	var err error
+	var before *service.Movie
+	if id != 0 {
+		before, err = selectMovie(ctx, dbTx, id)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+	}
+
+	if id, err = fn(dbTx); err != nil {
+		return 0, err
+	}
+
+	after, err := selectMovie(ctx, dbTx, id)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	if before == nil && after != nil {
+		action = service.HistoryCreate
+	}
+	if after != nil && (before == nil || before.Version != after.Version) {
+		if err := recordChange(ctx, dbTx, action, before, after); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+// recordChange inserts a history entry for a change from before to after,
+// made by the actor from ctx.
+func recordChange(ctx context.Context, dbTx *sql.Tx, action string, before, after *service.Movie) error {
+	movie := after
+	if movie == nil {
+		movie = before
+	}
+
+	beforeJSON, err := snapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := snapshot(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbTx.ExecContext(ctx, `
+		INSERT INTO movie_history (movie_id, version, action, actor, before, after, created_at, owner_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+	`, movie.ID, movie.Version, action, service.ActorFromContext(ctx),
+		beforeJSON, afterJSON, time.Now(), movie.OwnerID)
+
+	return err
+}
+
+// snapshot encodes a movie as JSON for the history, or returns nil for a
+// nil movie. Ratings and tags are left out as they are not changed with
+// the movie.
+func snapshot(movie *service.Movie) (interface{}, error) {
+	if movie == nil {
+		return nil, nil
+	}
+
+	m := *movie
+	m.AverageRating, m.RatingCount, m.Tags = 0, 0, nil
+	b, err := json.Marshal(&m)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// GetHistory returns the recorded changes to a movie from the database,
+// most recent first. The history of a purged movie is still returned.
+func (s *MovieService) GetHistory(ctx context.Context, id int64) (*service.History, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, movie_id, version, action, actor, before, after, created_at,
+			owner_id
+		FROM movie_history
+		WHERE movie_id = $1 AND $2 IN (0, owner_id)
+		ORDER BY id DESC;
+	`, id, service.UserIDFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := service.History{}
+	for rows.Next() {
+		var entry service.HistoryEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.MovieID, &entry.Version,
+			&entry.Action, &entry.Actor, &before, &after, &entry.CreatedAt,
+			&entry.OwnerID); err != nil {
+			return nil, err
+		}
+
+		if entry.Before, err = parseSnapshot(before); err != nil {
+			return nil, err
+		}
+		if entry.After, err = parseSnapshot(after); err != nil {
+			return nil, err
+		}
+
+		history = append(history, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Movies changed before the history was recorded have no entries.
+	if len(history) == 0 {
+		if _, err := s.GetMovie(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return &history, nil
+}
+
+// RevertMovie sets the title, IMDb id and metadata of an existing movie in
+// the database back to how they were at a version in its history.
+func (s *MovieService) RevertMovie(ctx context.Context, id int64, version int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	_, err := s.change(ctx, service.HistoryRevert, id, func(dbTx *sql.Tx) (int64, error) {
+		movie, err := selectMovie(ctx, dbTx, id)
+		if err != nil {
+			return 0, movieError(err)
+		}
+
+		var after sql.NullString
+		err = dbTx.QueryRowContext(ctx, `
+			SELECT after
+			FROM movie_history
+			WHERE movie_id = $1 AND version = $2 AND after IS NOT NULL
+			ORDER BY id DESC
+			LIMIT 1;
+		`, id, version).Scan(&after)
+		if err == sql.ErrNoRows {
+			return 0, service.ErrVersionNotFound
+		} else if err != nil {
+			return 0, err
+		}
+
+		target, err := parseSnapshot(after)
+		if err != nil {
+			return 0, err
+		}
+
+		return id, patchMovie(ctx, dbTx, movie, service.NewMovieUpdate(movie, target))
+	})
+
+	return err
+}
+
+// parseSnapshot decodes a movie recorded by snapshot.
+func parseSnapshot(s sql.NullString) (*service.Movie, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	var movie service.Movie
+	if err := json.Unmarshal([]byte(s.String), &movie); err != nil {
+		return nil, err
+	}
+
+	return &movie, nil
+}
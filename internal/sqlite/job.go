@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"../service"
+)
+
+// Ensure JobService implements service.JobService.
+var _ service.JobService = &JobService{}
+
+// JobService represents a SQLite implementation of a JobService.
+type JobService struct {
+	DB *sql.DB
+	Timeout
+}
+
+// GetJobs returns the jobs run for a movie from the database, most
+// recent first.
+func (s *JobService) GetJobs(ctx context.Context, movieID int64) (*service.Jobs, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, kind, movie_id, status, attempts, error, created_at, updated_at
+		FROM jobs
+		WHERE movie_id = $1
+		ORDER BY id DESC;
+	`, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs service.Jobs
+	for rows.Next() {
+		var job service.Job
+		if err := rows.Scan(&job.ID, &job.Kind, &job.MovieID, &job.Status,
+			&job.Attempts, &job.Error, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &jobs, nil
+}
+
+// CreateJob adds a new job to the database.
+func (s *JobService) CreateJob(ctx context.Context, job *service.Job) (int64, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	res, err := s.DB.ExecContext(ctx, `
+		INSERT INTO jobs (kind, movie_id, status, attempts, error, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6);
+	`, job.Kind, job.MovieID, job.Status, job.Attempts, job.Error, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// UpdateJob updates the status of an existing job in the database.
+func (s *JobService) UpdateJob(ctx context.Context, id int64, job *service.Job) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, `
+		UPDATE jobs
+		SET id = $1, status = $2, attempts = $3, error = $4, updated_at = $5
+		WHERE id = $1;
+	`, id, job.Status, job.Attempts, job.Error, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,658 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// migration is a single versioned change to the database schema. The up
+// statement applies the change and the down statement reverts it, or is
+// empty if the change cannot be reverted. Fts5 is set if the change creates
+// an FTS5 table.
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+	fts5    bool
+}
+
+// migrations is the ordered list of every schema change. New migrations
+// must be appended with the next version number and never edited once
+// released, otherwise existing databases will drift from the schema.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create_movies_table",
+		up: `
+			CREATE TABLE IF NOT EXISTS movies(
+				id INTEGER PRIMARY KEY NOT NULL,
+				title VARCHAR(255) NOT NULL,
+				imdb_id VARCHAR(255) UNIQUE NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+
+				CHECK (length(title) > 0 AND length(imdb_id) > 0)
+			);
+		`,
+		down: `DROP TABLE IF EXISTS movies;`,
+	},
+	{
+		// Requires the sqlite_fts5 build tag for github.com/mattn/go-sqlite3.
+		version: 2,
+		name:    "create_movies_fts_table",
+		fts5:    true,
+		up: `
+			CREATE VIRTUAL TABLE movies_fts USING fts5(
+				title,
+				content='movies',
+				content_rowid='id'
+			);
+
+			CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+				INSERT INTO movies_fts (rowid, title) VALUES (new.id, new.title);
+			END;
+
+			CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+				INSERT INTO movies_fts (movies_fts, rowid, title)
+				VALUES ('delete', old.id, old.title);
+			END;
+
+			CREATE TRIGGER movies_fts_update AFTER UPDATE ON movies BEGIN
+				INSERT INTO movies_fts (movies_fts, rowid, title)
+				VALUES ('delete', old.id, old.title);
+				INSERT INTO movies_fts (rowid, title) VALUES (new.id, new.title);
+			END;
+
+			INSERT INTO movies_fts (movies_fts) VALUES ('rebuild');
+		`,
+		down: `
+			DROP TRIGGER IF EXISTS movies_fts_update;
+			DROP TRIGGER IF EXISTS movies_fts_delete;
+			DROP TRIGGER IF EXISTS movies_fts_insert;
+			DROP TABLE IF EXISTS movies_fts;
+		`,
+	},
+	{
+		version: 3,
+		name:    "add_movies_metadata_columns",
+		up: `
+			ALTER TABLE movies ADD COLUMN year INTEGER DEFAULT 0 NOT NULL;
+			ALTER TABLE movies ADD COLUMN runtime INTEGER DEFAULT 0 NOT NULL;
+			ALTER TABLE movies ADD COLUMN genres TEXT DEFAULT '' NOT NULL;
+			ALTER TABLE movies ADD COLUMN director TEXT DEFAULT '' NOT NULL;
+			ALTER TABLE movies ADD COLUMN actors TEXT DEFAULT '' NOT NULL;
+			ALTER TABLE movies ADD COLUMN plot TEXT DEFAULT '' NOT NULL;
+			ALTER TABLE movies ADD COLUMN poster_url TEXT DEFAULT '' NOT NULL;
+			ALTER TABLE movies ADD COLUMN enriched_at DATETIME;
+		`,
+		down: `
+			ALTER TABLE movies DROP COLUMN enriched_at;
+			ALTER TABLE movies DROP COLUMN poster_url;
+			ALTER TABLE movies DROP COLUMN plot;
+			ALTER TABLE movies DROP COLUMN actors;
+			ALTER TABLE movies DROP COLUMN director;
+			ALTER TABLE movies DROP COLUMN genres;
+			ALTER TABLE movies DROP COLUMN runtime;
+			ALTER TABLE movies DROP COLUMN year;
+		`,
+	},
+	{
+		// Rebuilds the search index to cover the metadata columns.
+		version: 4,
+		name:    "add_metadata_to_movies_fts",
+		fts5:    true,
+		up: `
+			DROP TRIGGER movies_fts_update;
+			DROP TRIGGER movies_fts_delete;
+			DROP TRIGGER movies_fts_insert;
+			DROP TABLE movies_fts;
+
+			CREATE VIRTUAL TABLE movies_fts USING fts5(
+				title,
+				director,
+				actors,
+				plot,
+				content='movies',
+				content_rowid='id'
+			);
+
+			CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+				INSERT INTO movies_fts (rowid, title, director, actors, plot)
+				VALUES (new.id, new.title, new.director, new.actors, new.plot);
+			END;
+
+			CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+				INSERT INTO movies_fts (movies_fts, rowid, title, director, actors, plot)
+				VALUES ('delete', old.id, old.title, old.director, old.actors, old.plot);
+			END;
+
+			CREATE TRIGGER movies_fts_update AFTER UPDATE ON movies BEGIN
+				INSERT INTO movies_fts (movies_fts, rowid, title, director, actors, plot)
+				VALUES ('delete', old.id, old.title, old.director, old.actors, old.plot);
+				INSERT INTO movies_fts (rowid, title, director, actors, plot)
+				VALUES (new.id, new.title, new.director, new.actors, new.plot);
+			END;
+
+			INSERT INTO movies_fts (movies_fts) VALUES ('rebuild');
+		`,
+		down: `
+			DROP TRIGGER movies_fts_update;
+			DROP TRIGGER movies_fts_delete;
+			DROP TRIGGER movies_fts_insert;
+			DROP TABLE movies_fts;
+
+			CREATE VIRTUAL TABLE movies_fts USING fts5(
+				title,
+				content='movies',
+				content_rowid='id'
+			);
+
+			CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+				INSERT INTO movies_fts (rowid, title) VALUES (new.id, new.title);
+			END;
+
+			CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+				INSERT INTO movies_fts (movies_fts, rowid, title)
+				VALUES ('delete', old.id, old.title);
+			END;
+
+			CREATE TRIGGER movies_fts_update AFTER UPDATE ON movies BEGIN
+				INSERT INTO movies_fts (movies_fts, rowid, title)
+				VALUES ('delete', old.id, old.title);
+				INSERT INTO movies_fts (rowid, title) VALUES (new.id, new.title);
+			END;
+
+			INSERT INTO movies_fts (movies_fts) VALUES ('rebuild');
+		`,
+	},
+	{
+		version: 5,
+		name:    "create_jobs_table",
+		up: `
+			CREATE TABLE jobs(
+				id INTEGER PRIMARY KEY NOT NULL,
+				kind VARCHAR(255) NOT NULL,
+				movie_id INTEGER NOT NULL,
+				status VARCHAR(255) NOT NULL,
+				attempts INTEGER DEFAULT 0 NOT NULL,
+				error TEXT DEFAULT '' NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+			);
+
+			CREATE INDEX jobs_movie_id ON jobs (movie_id);
+		`,
+		down: `DROP TABLE jobs;`,
+	},
+	{
+		version: 6,
+		name:    "add_movies_version_column",
+		up:      `ALTER TABLE movies ADD COLUMN version INTEGER DEFAULT 1 NOT NULL;`,
+		down:    `ALTER TABLE movies DROP COLUMN version;`,
+	},
+	{
+		version: 7,
+		name:    "add_movies_deleted_at_column",
+		up: `
+			ALTER TABLE movies ADD COLUMN deleted_at DATETIME;
+
+			CREATE INDEX movies_deleted_at ON movies (deleted_at);
+		`,
+		down: `
+			DROP INDEX movies_deleted_at;
+
+			ALTER TABLE movies DROP COLUMN deleted_at;
+		`,
+	},
+	{
+		version: 8,
+		name:    "create_movie_history_table",
+		up: `
+			CREATE TABLE movie_history(
+				id INTEGER PRIMARY KEY NOT NULL,
+				movie_id INTEGER NOT NULL,
+				version INTEGER NOT NULL,
+				action VARCHAR(255) NOT NULL,
+				actor VARCHAR(255) NOT NULL,
+				before TEXT,
+				after TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+			);
+
+			CREATE INDEX movie_history_movie_id ON movie_history (movie_id, version);
+		`,
+		down: `DROP TABLE movie_history;`,
+	},
+	{
+		version: 9,
+		name:    "create_users_and_sessions_tables",
+		up: `
+			CREATE TABLE users(
+				id INTEGER PRIMARY KEY NOT NULL,
+				username VARCHAR(255) NOT NULL COLLATE NOCASE UNIQUE,
+				password_hash VARCHAR(255) NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+			);
+
+			CREATE TABLE sessions(
+				id INTEGER PRIMARY KEY NOT NULL,
+				token_hash VARCHAR(255) NOT NULL UNIQUE,
+				user_id INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				expires_at DATETIME NOT NULL
+			);
+
+			CREATE INDEX sessions_user_id ON sessions (user_id);
+		`,
+		down: `
+			DROP TABLE sessions;
+			DROP TABLE users;
+		`,
+	},
+	{
+		version: 10,
+		name:    "create_api_tokens_table",
+		up: `
+			CREATE TABLE api_tokens(
+				id INTEGER PRIMARY KEY NOT NULL,
+				user_id INTEGER NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				scope VARCHAR(255) NOT NULL,
+				prefix VARCHAR(255) NOT NULL,
+				token_hash VARCHAR(255) NOT NULL UNIQUE,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				expires_at DATETIME,
+				last_used_at DATETIME,
+				revoked_at DATETIME
+
+				CHECK (scope IN ('read', 'write'))
+			);
+
+			CREATE INDEX api_tokens_user_id ON api_tokens (user_id);
+		`,
+		down: `DROP TABLE api_tokens;`,
+	},
+	{
+		// SQLite cannot drop the UNIQUE constraint on imdb_id, so the
+		// movies table is rebuilt, along with its indexes and search
+		// triggers. Existing movies go to the first user, if there is one.
+		// It has no down statement, since restoring the UNIQUE constraint
+		// fails as soon as two users own the same movie.
+		version: 11,
+		name:    "add_movies_owner_id_column",
+		up: `
+			CREATE TABLE movies_new(
+				id INTEGER PRIMARY KEY NOT NULL,
+				title VARCHAR(255) NOT NULL,
+				imdb_id VARCHAR(255) NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				year INTEGER DEFAULT 0 NOT NULL,
+				runtime INTEGER DEFAULT 0 NOT NULL,
+				genres TEXT DEFAULT '' NOT NULL,
+				director TEXT DEFAULT '' NOT NULL,
+				actors TEXT DEFAULT '' NOT NULL,
+				plot TEXT DEFAULT '' NOT NULL,
+				poster_url TEXT DEFAULT '' NOT NULL,
+				enriched_at DATETIME,
+				version INTEGER DEFAULT 1 NOT NULL,
+				deleted_at DATETIME,
+				owner_id INTEGER DEFAULT 0 NOT NULL
+
+				CHECK (length(title) > 0 AND length(imdb_id) > 0)
+			);
+
+			INSERT INTO movies_new (id, title, imdb_id, created_at, updated_at, year, runtime, genres,
+				director, actors, plot, poster_url, enriched_at, version, deleted_at, owner_id)
+			SELECT id, title, imdb_id, created_at, updated_at, year, runtime, genres,
+				director, actors, plot, poster_url, enriched_at, version, deleted_at,
+				COALESCE((SELECT MIN(id) FROM users), 0)
+			FROM movies;
+
+			DROP TABLE movies;
+			ALTER TABLE movies_new RENAME TO movies;
+
+			CREATE UNIQUE INDEX movies_owner_id_imdb_id ON movies (owner_id, imdb_id);
+			CREATE INDEX movies_deleted_at ON movies (deleted_at);
+
+			CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+				INSERT INTO movies_fts (rowid, title, director, actors, plot)
+				VALUES (new.id, new.title, new.director, new.actors, new.plot);
+			END;
+
+			CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+				INSERT INTO movies_fts (movies_fts, rowid, title, director, actors, plot)
+				VALUES ('delete', old.id, old.title, old.director, old.actors, old.plot);
+			END;
+
+			CREATE TRIGGER movies_fts_update AFTER UPDATE ON movies BEGIN
+				INSERT INTO movies_fts (movies_fts, rowid, title, director, actors, plot)
+				VALUES ('delete', old.id, old.title, old.director, old.actors, old.plot);
+				INSERT INTO movies_fts (rowid, title, director, actors, plot)
+				VALUES (new.id, new.title, new.director, new.actors, new.plot);
+			END;
+
+			INSERT INTO movies_fts (movies_fts) VALUES ('rebuild');
+
+			ALTER TABLE movie_history ADD COLUMN owner_id INTEGER DEFAULT 0 NOT NULL;
+
+			UPDATE movie_history
+			SET owner_id = COALESCE((SELECT MIN(id) FROM users), 0);
+		`,
+	},
+	{
+		version: 12,
+		name:    "create_reviews_table",
+		up: `
+			CREATE TABLE reviews(
+				movie_id INTEGER PRIMARY KEY NOT NULL,
+				score INTEGER NOT NULL,
+				scale INTEGER NOT NULL,
+				text TEXT DEFAULT '' NOT NULL,
+				watched_on VARCHAR(10) DEFAULT '' NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+
+				CHECK (score BETWEEN 1 AND scale)
+			);
+		`,
+		down: `DROP TABLE reviews;`,
+	},
+	{
+		version: 13,
+		name:    "create_viewings_table",
+		up: `
+			CREATE TABLE viewings(
+				id INTEGER PRIMARY KEY NOT NULL,
+				movie_id INTEGER NOT NULL,
+				watched_on VARCHAR(10) NOT NULL,
+				location VARCHAR(255) DEFAULT '' NOT NULL,
+				medium VARCHAR(255) DEFAULT '' NOT NULL,
+				notes TEXT DEFAULT '' NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+			);
+
+			CREATE INDEX viewings_movie_id ON viewings (movie_id);
+			CREATE INDEX viewings_watched_on ON viewings (watched_on);
+		`,
+		down: `DROP TABLE viewings;`,
+	},
+	{
+		version: 14,
+		name:    "create_watchlist_table",
+		up: `
+			CREATE TABLE watchlist(
+				movie_id INTEGER PRIMARY KEY NOT NULL,
+				status VARCHAR(255) NOT NULL,
+				position INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+
+				CHECK (status IN ('want-to-watch', 'watching', 'watched', 'abandoned'))
+			);
+
+			CREATE INDEX watchlist_position ON watchlist (position);
+		`,
+		down: `DROP TABLE watchlist;`,
+	},
+	{
+		version: 15,
+		name:    "create_tags_and_collections_tables",
+		up: `
+			CREATE TABLE tags(
+				id INTEGER PRIMARY KEY NOT NULL,
+				owner_id INTEGER NOT NULL,
+				name VARCHAR(255) NOT NULL COLLATE NOCASE,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+				UNIQUE (owner_id, name)
+			);
+
+			CREATE TABLE movie_tags(
+				movie_id INTEGER NOT NULL,
+				tag_id INTEGER NOT NULL,
+
+				PRIMARY KEY (movie_id, tag_id)
+			);
+
+			CREATE INDEX movie_tags_tag_id ON movie_tags (tag_id);
+
+			CREATE TABLE collections(
+				id INTEGER PRIMARY KEY NOT NULL,
+				owner_id INTEGER NOT NULL,
+				name VARCHAR(255) NOT NULL COLLATE NOCASE,
+				description TEXT DEFAULT '' NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+				UNIQUE (owner_id, name)
+			);
+
+			CREATE TABLE collection_movies(
+				collection_id INTEGER NOT NULL,
+				movie_id INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+				PRIMARY KEY (collection_id, movie_id)
+			);
+
+			CREATE INDEX collection_movies_movie_id ON collection_movies (movie_id);
+		`,
+		down: `
+			DROP TABLE collection_movies;
+			DROP TABLE collections;
+			DROP TABLE movie_tags;
+			DROP TABLE tags;
+		`,
+	},
+	{
+		version: 16,
+		name:    "create_copies_table",
+		up: `
+			CREATE TABLE copies(
+				id INTEGER PRIMARY KEY NOT NULL,
+				movie_id INTEGER NOT NULL,
+				format VARCHAR(255) NOT NULL,
+				edition VARCHAR(255) DEFAULT '' NOT NULL,
+				region VARCHAR(255) DEFAULT '' NOT NULL,
+				barcode VARCHAR(255) DEFAULT '' NOT NULL,
+				purchased_on VARCHAR(10) DEFAULT '' NOT NULL,
+				price_cents INTEGER DEFAULT 0 NOT NULL,
+				location VARCHAR(255) DEFAULT '' NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+			);
+
+			CREATE INDEX copies_movie_id ON copies (movie_id);
+			CREATE INDEX copies_barcode ON copies (barcode);
+		`,
+		down: `DROP TABLE copies;`,
+	},
+	{
+		// Databases that ran create_reviews_table before this migration
+		// was split out of it already have the index.
+		version: 17,
+		name:    "create_movies_imdb_id_index",
+		up:      `CREATE INDEX IF NOT EXISTS movies_imdb_id ON movies (imdb_id);`,
+		down:    `DROP INDEX IF EXISTS movies_imdb_id;`,
+	},
+}
+
+// ErrNoFTS5 is returned by MigrateUp when SQLite was built without FTS5,
+// which full-text search needs.
+var ErrNoFTS5 = errors.New("SQLite FTS5 is not enabled, build pmdb with -tags sqlite_fts5")
+
+// ErrIrreversible is returned by MigrateDown when the latest migration
+// cannot be reverted.
+var ErrIrreversible = errors.New("migration cannot be reverted")
+
+// MigrationStatus describes a migration and when it was applied. AppliedAt
+// is the zero time if the migration is still pending.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// Applied reports whether the migration has been applied.
+func (m MigrationStatus) Applied() bool {
+	return !m.AppliedAt.IsZero()
+}
+
+// MigrateUp applies every pending migration in version order. Each
+// migration runs in its own transaction together with its entry in the
+// schema_migrations table, so a failure leaves earlier migrations intact.
+func MigrateUp(db *sql.DB) error {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		if m.fts5 {
+			if err := checkFTS5(db); err != nil {
+				return fmt.Errorf("migration %d (%s): %v", m.version, m.name, err)
+			}
+		}
+
+		if err := runMigration(db, m.up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				INSERT INTO schema_migrations (version, name, applied_at)
+				VALUES ($1, $2, $3);
+			`, m.version, m.name, time.Now())
+			return err
+		}); err != nil {
+			return fmt.Errorf("migration %d (%s): %v", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// MigrateDown reverts the most recently applied migration. It does nothing
+// if no migrations have been applied, and fails with ErrIrreversible if the
+// migration cannot be reverted.
+func MigrateDown(db *sql.DB) error {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.version]; !ok {
+			continue
+		}
+
+		if m.down == "" {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, ErrIrreversible)
+		}
+
+		if err := runMigration(db, m.down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1;`, m.version)
+			return err
+		}); err != nil {
+			return fmt.Errorf("migration %d (%s): %v", m.version, m.name, err)
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// Migrations returns the status of every known migration in version order.
+func Migrations(db *sql.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range migrations {
+		statuses = append(statuses, MigrationStatus{
+			Version:   m.version,
+			Name:      m.name,
+			AppliedAt: applied[m.version],
+		})
+	}
+
+	return statuses, nil
+}
+
+// runMigration executes a migration statement and records the change in
+// the schema_migrations table within a single transaction.
+func runMigration(db *sql.DB, stmt string, record func(tx *sql.Tx) error) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err = dbTx.Exec(stmt); err != nil {
+		dbTx.Rollback()
+		return err
+	}
+
+	if err = record(dbTx); err != nil {
+		dbTx.Rollback()
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+// checkFTS5 returns ErrNoFTS5 if SQLite was built without FTS5.
+func checkFTS5(db *sql.DB) error {
+	var enabled bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5');`).Scan(&enabled); err != nil {
+		return err
+	}
+
+	if !enabled {
+		return ErrNoFTS5
+	}
+
+	return nil
+}
+
+// appliedMigrations creates the schema_migrations table if one doesn't
+// already exist and returns the applied migration versions mapped to the
+// time they were applied.
+func appliedMigrations(db *sql.DB) (map[int]time.Time, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations(
+			version INTEGER PRIMARY KEY NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
@@ -0,0 +1,154 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// openTestDB opens an empty database in a temporary directory. Tests are
+// skipped if SQLite was built without FTS5, which the migrations need.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "pmdb.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := checkFTS5(db); err == ErrNoFTS5 {
+		t.Skip("run with -tags sqlite_fts5")
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// schema returns the SQL of every table, index and trigger in db by name,
+// leaving out the migrations table.
+func schema(t *testing.T, db *sql.DB) map[string]string {
+	t.Helper()
+
+	rows, err := db.Query(`
+		SELECT name, COALESCE(sql, '')
+		FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' AND name != 'schema_migrations';
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	s := make(map[string]string)
+	for rows.Next() {
+		var name, stmt string
+		if err := rows.Scan(&name, &stmt); err != nil {
+			t.Fatal(err)
+		}
+		s[name] = stmt
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// applied returns the versions of the applied migrations in order.
+func applied(t *testing.T, db *sql.DB) []int {
+	t.Helper()
+
+	statuses, err := Migrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var versions []int
+	for _, m := range statuses {
+		if m.Applied() {
+			versions = append(versions, m.Version)
+		}
+	}
+
+	return versions
+}
+
+func TestMigrationsOrdered(t *testing.T) {
+	names := make(map[string]bool)
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %q has version %d, want %d", m.name, m.version, i+1)
+		}
+		if names[m.name] {
+			t.Errorf("migration name %q is used more than once", m.name)
+		}
+		names[m.name] = true
+		if m.up == "" {
+			t.Errorf("migration %d (%s) is missing an up statement", m.version, m.name)
+		}
+	}
+}
+
+func TestMigrateUpDownUp(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp() error = %v", err)
+	}
+	if got := applied(t, db); len(got) != len(migrations) {
+		t.Fatalf("applied %v after MigrateUp, want all %d migrations", got, len(migrations))
+	}
+	want := schema(t, db)
+
+	// Running MigrateUp again does nothing.
+	if err := MigrateUp(db); err != nil {
+		t.Fatalf("second MigrateUp() error = %v", err)
+	}
+	if got := schema(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("second MigrateUp() changed the schema")
+	}
+
+	// Every MigrateDown reverts only the latest migration, until one that
+	// cannot be reverted.
+	i := len(migrations) - 1
+	for ; migrations[i].down != ""; i-- {
+		if err := MigrateDown(db); err != nil {
+			t.Fatalf("MigrateDown() of migration %d (%s) error = %v",
+				migrations[i].version, migrations[i].name, err)
+		}
+		if got := applied(t, db); len(got) != i {
+			t.Fatalf("applied %v after reverting migration %d, want %d migrations",
+				got, migrations[i].version, i)
+		}
+	}
+	if err := MigrateDown(db); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("MigrateDown() of migration %d (%s) error = %v, want ErrIrreversible",
+			migrations[i].version, migrations[i].name, err)
+	}
+	if got := applied(t, db); len(got) != i+1 {
+		t.Fatalf("applied %v after failing to revert migration %d, want %d migrations",
+			got, migrations[i].version, i+1)
+	}
+
+	if err := MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp() after reverting error = %v", err)
+	}
+	if got := schema(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("schema after migrating up again differs from the first time")
+	}
+}
+
+func TestMigrateDownNothingApplied(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := MigrateDown(db); err != nil {
+		t.Fatalf("MigrateDown() with nothing applied error = %v", err)
+	}
+	if got := applied(t, db); len(got) != 0 {
+		t.Errorf("applied %v after MigrateDown, want none", got)
+	}
+}
@@ -1,92 +1,772 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
 	"time"
 
 	"../service"
+	"github.com/mattn/go-sqlite3"
 )
 
+// Ensure MovieService implements service.MovieService.
+var _ service.MovieService = &MovieService{}
+
 // MovieService represents a SQLite implementation of a MovieService.
 type MovieService struct {
 	DB *sql.DB
+	Timeout
+
+	// RatingScale is the scale of the average ratings of movies. A zero
+	// value means service.DefaultRatingScale.
+	RatingScale int
 }
 
-// GetMovies returns all movies from the database.
-func (s *MovieService) GetMovies() (*service.Movies, error) {
-	rows, err := s.DB.Query(`SELECT * FROM movies;`)
-	defer rows.Close()
+// GetMovies returns the movies from the database matching the filter,
+// along with a cursor for the next page if there are more movies.
+func (s *MovieService) GetMovies(ctx context.Context, f service.MovieFilter) (*service.Movies, *service.Cursor, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	query, args, err := moviesQuery(f, service.UserIDFromContext(ctx))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
 	}
+	defer rows.Close()
 
 	var movies service.Movies
 	for rows.Next() {
-		var movie service.Movie
-		if err := rows.Scan(&movie.ID, &movie.Title, &movie.ImdbID,
-			&movie.CreatedAt, &movie.UpdatedAt); err != nil {
-			return nil, err
+		movie, err := scanMovie(rows)
+		if err != nil {
+			return nil, nil, err
 		}
-		movies = append(movies, &movie)
+		movies = append(movies, movie)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	s.rate(movies...)
+
+	// One more row than the limit is requested to find out whether
+	// there is a next page.
+	var next *service.Cursor
+	if f.Limit > 0 && len(movies) > f.Limit {
+		movies = movies[:f.Limit]
+		next = service.NewCursor(f, movies[len(movies)-1])
 	}
 
-	return &movies, nil
+	return &movies, next, nil
 }
 
 // GetMovie returns a single movie from the database.
-func (s *MovieService) GetMovie(id int64) (*service.Movie, error) {
-	row := s.DB.QueryRow(`
-		SELECT id, title, imdb_id, created_at, updated_at
+func (s *MovieService) GetMovie(ctx context.Context, id int64) (*service.Movie, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	row := s.DB.QueryRowContext(ctx, `
+		SELECT `+movieColumns+`
 		FROM movies
-		WHERE id = $1;
-	`, id)
-	var movie service.Movie
-	if err := row.Scan(&movie.ID, &movie.Title, &movie.ImdbID,
-		&movie.CreatedAt, &movie.UpdatedAt); err != nil {
+		WHERE id = $1 AND $2 IN (0, owner_id);
+	`, id, service.UserIDFromContext(ctx))
+
+	movie, err := scanMovie(row)
+	if err != nil {
+		return nil, movieError(err)
+	}
+	s.rate(movie)
+
+	return movie, nil
+}
+
+// GetMovieByImdbID returns the movie with the given IMDb id from the
+// database.
+func (s *MovieService) GetMovieByImdbID(ctx context.Context, imdbID string) (*service.Movie, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	row := s.DB.QueryRowContext(ctx, `
+		SELECT `+movieColumns+`
+		FROM movies
+		WHERE imdb_id = $1 AND $2 IN (0, owner_id)
+		ORDER BY id;
+	`, imdbID, service.UserIDFromContext(ctx))
+
+	movie, err := scanMovie(row)
+	if err != nil {
+		return nil, movieError(err)
+	}
+	s.rate(movie)
+
+	return movie, nil
+}
+
+// SearchMovies returns the movies from the database matching the search
+// query, most relevant first. Every term in the query must match the
+// start of a word in the movie.
+func (s *MovieService) SearchMovies(ctx context.Context, query string, limit int) (*service.SearchResults, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	// Quote each term and mark it as a prefix so the query can never be
+	// parsed as FTS5 syntax.
+	var terms []string
+	for _, t := range service.SearchTerms(query) {
+		terms = append(terms, `"`+t+`"*`)
+	}
+
+	results := service.SearchResults{}
+	if len(terms) == 0 {
+		return &results, nil
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT `+movieColumns+`, r.rank, r.snippet
+		FROM movies
+		JOIN (
+			SELECT rowid, rank,
+				snippet(movies_fts, -1, $1, $2, '...', 16) AS snippet
+			FROM movies_fts
+			WHERE movies_fts MATCH $3
+		) r ON r.rowid = movies.id
+		WHERE movies.deleted_at IS NULL AND $4 IN (0, movies.owner_id)
+		ORDER BY r.rank
+		LIMIT $5;
+	`, service.SnippetStart, service.SnippetEnd, strings.Join(terms, " "),
+		service.UserIDFromContext(ctx), limit)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &movie, nil
+	for rows.Next() {
+		var result service.SearchResult
+		result.Movie, err = scanMovie(rows, &result.Rank, &result.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		result.Snippet = service.MarkSnippet(result.Snippet)
+		results = append(results, &result)
+		s.rate(result.Movie)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
 }
 
 // CreateMovie adds a new movie to the database.
-func (s *MovieService) CreateMovie(movie *service.Movie) (int64, error) {
-	res, err := s.DB.Exec(`
-		INSERT INTO movies (title, imdb_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $3);
-	`, movie.Title, movie.ImdbID, time.Now())
+func (s *MovieService) CreateMovie(ctx context.Context, movie *service.Movie) (int64, error) {
+	movie.Normalize()
+	if err := movie.Validate(); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	return s.change(ctx, service.HistoryCreate, 0, func(dbTx *sql.Tx) (int64, error) {
+		return insertMovie(ctx, dbTx, movie)
+	})
+}
+
+// UpdateMovie updates an existing movie in the database.
+func (s *MovieService) UpdateMovie(ctx context.Context, id int64, movie *service.Movie) error {
+	movie.Normalize()
+	if err := movie.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	_, err := s.change(ctx, service.HistoryUpdate, id, func(dbTx *sql.Tx) (int64, error) {
+		res, err := dbTx.ExecContext(ctx, `
+			UPDATE movies
+			SET id = $1, title = $2, imdb_id = $3, updated_at = $4,
+				version = version + 1
+			WHERE id = $1 AND $5 IN (0, version) AND $6 IN (0, owner_id);
+		`, id, movie.Title, movie.ImdbID, time.Now(), movie.Version,
+			service.UserIDFromContext(ctx))
+		if err != nil {
+			return 0, movieError(err)
+		}
+
+		return id, checkVersion(res, movie.Version)
+	})
+
+	return err
+}
+
+// UpsertMovie creates a movie, or updates the title of the movie with the
+// same IMDb id in the library if there is one, restoring it if it is in
+// the trash. It reports whether the movie was created. A non-zero version
+// requires the movie to exist at that version.
+func (s *MovieService) UpsertMovie(ctx context.Context, movie *service.Movie) (int64, bool, error) {
+	movie.Normalize()
+	if err := movie.Validate(); err != nil {
+		return 0, false, err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	// Look the movie up in the same transaction as the write, so a
+	// concurrent upsert cannot create it in between.
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer dbTx.Rollback()
+
+	var id int64
+	err = dbTx.QueryRowContext(ctx, `
+		SELECT id
+		FROM movies
+		WHERE imdb_id = $1 AND owner_id = $2;
+	`, movie.ImdbID, service.UserIDFromContext(ctx)).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, false, err
+	}
+
+	created := id == 0
+	id, err = changeTx(ctx, dbTx, service.HistoryUpdate, id, func(dbTx *sql.Tx) (int64, error) {
+		if created && movie.Version != 0 {
+			return 0, service.ErrMovieChanged
+		} else if created {
+			return insertMovie(ctx, dbTx, movie)
+		}
+
+		res, err := dbTx.ExecContext(ctx, `
+			UPDATE movies
+			SET id = $1, title = $2, updated_at = $3, version = version + 1,
+				deleted_at = NULL
+			WHERE id = $1 AND $4 IN (0, version);
+		`, id, movie.Title, time.Now(), movie.Version)
+		if err != nil {
+			return 0, movieError(err)
+		}
+
+		return id, checkVersion(res, movie.Version)
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	return id, created, dbTx.Commit()
+}
+
+// PatchMovie updates only the fields of an existing movie in the database
+// that are included in the update. The patched movie is validated as a
+// whole.
+func (s *MovieService) PatchMovie(ctx context.Context, id int64, u *service.MovieUpdate) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	_, err := s.change(ctx, service.HistoryUpdate, id, func(dbTx *sql.Tx) (int64, error) {
+		movie, err := selectMovie(ctx, dbTx, id)
+		if err != nil {
+			return 0, movieError(err)
+		}
+		if u.Version != 0 && u.Version != movie.Version {
+			return 0, service.ErrMovieChanged
+		}
+
+		return id, patchMovie(ctx, dbTx, movie, u)
+	})
+
+	return err
+}
+
+// UpdateMetadata replaces the external metadata of an existing movie in
+// the database and records when it was fetched.
+func (s *MovieService) UpdateMetadata(ctx context.Context, id int64, md *service.Metadata) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	_, err := s.change(ctx, service.HistoryEnrich, id, func(dbTx *sql.Tx) (int64, error) {
+		_, err := dbTx.ExecContext(ctx, `
+			UPDATE movies
+			SET year = $1, runtime = $2, genres = $3, director = $4, actors = $5,
+				plot = $6, poster_url = $7, enriched_at = $8, updated_at = $8,
+				version = version + 1
+			WHERE id = $9 AND $10 IN (0, owner_id);
+		`, md.Year, md.Runtime, joinList(md.Genres), md.Director,
+			joinList(md.Cast), md.Plot, md.PosterURL, time.Now(), id,
+			service.UserIDFromContext(ctx))
+
+		return id, err
+	})
+
+	return err
+}
+
+// DeleteMovie moves an existing movie in the database to the trash.
+func (s *MovieService) DeleteMovie(ctx context.Context, id int64, version int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	_, err := s.change(ctx, service.HistoryDelete, id, func(dbTx *sql.Tx) (int64, error) {
+		// A movie already in the trash is not found, whatever the version.
+		var current int64
+		err := dbTx.QueryRowContext(ctx, `
+			SELECT version
+			FROM movies
+			WHERE id = $1 AND deleted_at IS NULL AND $2 IN (0, owner_id);
+		`, id, service.UserIDFromContext(ctx)).Scan(&current)
+		if err != nil {
+			return 0, movieError(err)
+		}
+		if version != 0 && version != current {
+			return 0, service.ErrMovieChanged
+		}
+
+		_, err = dbTx.ExecContext(ctx, `
+			UPDATE movies
+			SET deleted_at = $1, version = version + 1
+			WHERE id = $2;
+		`, time.Now(), id)
+
+		return id, err
+	})
+
+	return err
+}
+
+// RestoreMovie moves a movie in the trash back into the library. Restoring
+// a movie that is not in the trash does nothing.
+func (s *MovieService) RestoreMovie(ctx context.Context, id int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	_, err := s.change(ctx, service.HistoryRestore, id, func(dbTx *sql.Tx) (int64, error) {
+		if _, err := selectMovie(ctx, dbTx, id); err != nil {
+			return 0, movieError(err)
+		}
+
+		_, err := dbTx.ExecContext(ctx, `
+			UPDATE movies
+			SET deleted_at = NULL, version = version + 1
+			WHERE id = $1 AND deleted_at IS NOT NULL;
+		`, id)
+
+		return id, err
+	})
+
+	return err
+}
+
+// PurgeMovie permanently removes a movie in the trash, along with
+// everything belonging to it but its history, such as its jobs and
+// review, from the database.
+func (s *MovieService) PurgeMovie(ctx context.Context, id int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	n, err := s.purge(ctx, `WHERE id = $1 AND deleted_at IS NOT NULL AND $2 IN (0, owner_id)`,
+		id, service.UserIDFromContext(ctx))
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return service.ErrNotInTrash
+	}
+
+	return nil
+}
+
+// EmptyTrash permanently removes every movie in the trash, along with
+// everything belonging to them but their history, from the database and
+// returns how many were removed.
+func (s *MovieService) EmptyTrash(ctx context.Context) (int64, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	return s.purge(ctx, `WHERE deleted_at IS NOT NULL AND $1 IN (0, owner_id)`,
+		service.UserIDFromContext(ctx))
+}
+
+// purge deletes the movies matching the where clause, and everything
+// belonging to them but their history, in a single transaction and
+// returns how many movies were deleted. Every deleted movie is recorded in the history.
+func (s *MovieService) purge(ctx context.Context, where string, args ...interface{}) (int64, error) {
+	dbTx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
+	defer dbTx.Rollback()
 
-	id, err := res.LastInsertId()
+	rows, err := dbTx.QueryContext(ctx, `SELECT `+movieColumns+` FROM movies `+where+`;`, args...)
 	if err != nil {
 		return 0, err
 	}
 
-	return id, nil
+	var movies service.Movies
+	for rows.Next() {
+		movie, err := scanMovie(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		movies = append(movies, movie)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, movie := range movies {
+		if _, err := dbTx.ExecContext(ctx, `DELETE FROM jobs WHERE movie_id = $1;`, movie.ID); err != nil {
+			return 0, err
+		}
+		if _, err := dbTx.ExecContext(ctx, `DELETE FROM reviews WHERE movie_id = $1;`, movie.ID); err != nil {
+			return 0, err
+		}
+		if _, err := dbTx.ExecContext(ctx, `DELETE FROM viewings WHERE movie_id = $1;`, movie.ID); err != nil {
+			return 0, err
+		}
+		if _, err := dbTx.ExecContext(ctx, `DELETE FROM watchlist WHERE movie_id = $1;`, movie.ID); err != nil {
+			return 0, err
+		}
+		if _, err := dbTx.ExecContext(ctx, `DELETE FROM movie_tags WHERE movie_id = $1;`, movie.ID); err != nil {
+			return 0, err
+		}
+		if _, err := dbTx.ExecContext(ctx, `DELETE FROM collection_movies WHERE movie_id = $1;`, movie.ID); err != nil {
+			return 0, err
+		}
+		if _, err := dbTx.ExecContext(ctx, `DELETE FROM copies WHERE movie_id = $1;`, movie.ID); err != nil {
+			return 0, err
+		}
+		if _, err := dbTx.ExecContext(ctx, `DELETE FROM movies WHERE id = $1;`, movie.ID); err != nil {
+			return 0, err
+		}
+		if err := recordChange(ctx, dbTx, service.HistoryPurge, movie, nil); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(movies)), dbTx.Commit()
 }
 
-// UpdateMovie updates an existing movie in the database.
-func (s *MovieService) UpdateMovie(id int64, movie *service.Movie) error {
-	_, err := s.DB.Exec(`
-		UPDATE movies
-		SET id = $1, title = $2, imdb_id = $3, updated_at = $4
-		WHERE id = $1;
-	`, id, movie.Title, movie.ImdbID, time.Now())
+// insertMovie inserts a new movie into the library of the user in ctx and
+// returns its id.
+func insertMovie(ctx context.Context, dbTx *sql.Tx, movie *service.Movie) (int64, error) {
+	res, err := dbTx.ExecContext(ctx, `
+		INSERT INTO movies (title, imdb_id, created_at, updated_at, owner_id)
+		VALUES ($1, $2, $3, $3, $4);
+	`, movie.Title, movie.ImdbID, time.Now(), service.UserIDFromContext(ctx))
 	if err != nil {
+		return 0, movieError(err)
+	}
+
+	return res.LastInsertId()
+}
+
+// selectMovie returns the movie with the given id, or sql.ErrNoRows if it
+// does not exist or is in the library of a user other than the one in ctx.
+func selectMovie(ctx context.Context, dbTx *sql.Tx, id int64) (*service.Movie, error) {
+	row := dbTx.QueryRowContext(ctx, `
+		SELECT `+movieColumns+`
+		FROM movies
+		WHERE id = $1 AND $2 IN (0, owner_id);
+	`, id, service.UserIDFromContext(ctx))
+
+	return scanMovie(row)
+}
+
+// patchMovie applies the update to the movie, validates the result and
+// writes only the updated columns.
+func patchMovie(ctx context.Context, dbTx *sql.Tx, movie *service.Movie, u *service.MovieUpdate) error {
+	u.Apply(movie)
+	movie.Normalize()
+	if err := movie.Validate(); err != nil {
 		return err
 	}
 
+	var sets []string
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		sets = append(sets, column+" = ?")
+		args = append(args, value)
+	}
+	if u.Title != nil {
+		set("title", movie.Title)
+	}
+	if u.ImdbID != nil {
+		set("imdb_id", movie.ImdbID)
+	}
+	if u.Year != nil {
+		set("year", movie.Year)
+	}
+	if u.Runtime != nil {
+		set("runtime", movie.Runtime)
+	}
+	if u.Genres != nil {
+		set("genres", joinList(movie.Genres))
+	}
+	if u.Director != nil {
+		set("director", movie.Director)
+	}
+	if u.Cast != nil {
+		set("actors", joinList(movie.Cast))
+	}
+	if u.Plot != nil {
+		set("plot", movie.Plot)
+	}
+	if u.PosterURL != nil {
+		set("poster_url", movie.PosterURL)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	set("updated_at", time.Now())
+
+	_, err := dbTx.ExecContext(ctx, `
+		UPDATE movies
+		SET `+strings.Join(sets, ", ")+`, version = version + 1
+		WHERE id = ?;
+	`, append(args, movie.ID)...)
+	if err != nil {
+		return movieError(err)
+	}
+
 	return nil
 }
 
-// DeleteMovie removes an existing movie from the database.
-func (s *MovieService) DeleteMovie(id int64) error {
-	_, err := s.DB.Exec(`DELETE FROM movies WHERE id = $1;`, id)
+// checkVersion returns service.ErrMovieChanged if a write that was based
+// on version did not change any rows.
+func checkVersion(res sql.Result, version int64) error {
+	if version == 0 {
+		return nil
+	}
+
+	n, err := res.RowsAffected()
 	if err != nil {
 		return err
+	} else if n == 0 {
+		return service.ErrMovieChanged
 	}
 
 	return nil
 }
+
+// movieError translates a database error into a service error so that
+// missing movies and constraint violations can be reported to clients.
+// Other errors are returned unchanged.
+func movieError(err error) error {
+	if err == sql.ErrNoRows {
+		return service.Errorf(service.ENotFound, "movie not found")
+	}
+
+	var e sqlite3.Error
+	if errors.As(err, &e) && e.Code == sqlite3.ErrConstraint {
+		switch e.ExtendedCode {
+		case sqlite3.ErrConstraintUnique:
+			return &service.Error{
+				Code:    service.EConflict,
+				Message: "a movie with that IMDb id already exists",
+				Err:     err,
+			}
+		case sqlite3.ErrConstraintCheck, sqlite3.ErrConstraintNotNull:
+			return &service.Error{
+				Code:    service.EInvalid,
+				Message: "title and IMDb id are required",
+				Err:     err,
+			}
+		}
+	}
+
+	return err
+}
+
+// movieColumns lists the movies table columns in the order scanMovie
+// expects them, followed by the average review score, as a fraction of
+// the rating scale, and the number of reviews of the movie, and the tag
+// names as a JSON array.
+const movieColumns = `id, title, imdb_id, year, runtime, genres, director,
+	actors, plot, poster_url, enriched_at, created_at, updated_at, version,
+	deleted_at, owner_id,
+	(SELECT AVG(CAST(score AS REAL) / scale)
+		FROM reviews WHERE movie_id = movies.id),
+	(SELECT COUNT(*) FROM reviews WHERE movie_id = movies.id),
+	(SELECT json_group_array(name) FROM (
+		SELECT tags.name
+		FROM movie_tags JOIN tags ON tags.id = movie_tags.tag_id
+		WHERE movie_tags.movie_id = movies.id
+		ORDER BY tags.name))`
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMovie scans a row selected with movieColumns into a movie. Any
+// extra destinations are scanned from the columns that follow. The
+// average rating is left as a fraction of the rating scale; see
+// MovieService.rate.
+func scanMovie(row scanner, extra ...interface{}) (*service.Movie, error) {
+	var movie service.Movie
+	var genres, cast, tags string
+	var enrichedAt, deletedAt sql.NullTime
+	var rating sql.NullFloat64
+
+	dest := []interface{}{&movie.ID, &movie.Title, &movie.ImdbID,
+		&movie.Year, &movie.Runtime, &genres, &movie.Director, &cast,
+		&movie.Plot, &movie.PosterURL, &enrichedAt,
+		&movie.CreatedAt, &movie.UpdatedAt, &movie.Version, &deletedAt,
+		&movie.OwnerID, &rating, &movie.RatingCount, &tags}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	movie.Genres = splitList(genres)
+	movie.Cast = splitList(cast)
+	if enrichedAt.Valid {
+		movie.EnrichedAt = &enrichedAt.Time
+	}
+	if deletedAt.Valid {
+		movie.DeletedAt = &deletedAt.Time
+	}
+	movie.AverageRating = rating.Float64
+	if err := json.Unmarshal([]byte(tags), &movie.Tags); err != nil {
+		return nil, err
+	}
+	if len(movie.Tags) == 0 {
+		movie.Tags = nil
+	}
+
+	return &movie, nil
+}
+
+// rate scales the average rating of the movies, as scanned by scanMovie,
+// to the rating scale of the service, rounded to one decimal place.
+func (s *MovieService) rate(movies ...*service.Movie) {
+	scale := s.RatingScale
+	if scale == 0 {
+		scale = service.DefaultRatingScale
+	}
+
+	for _, m := range movies {
+		m.AverageRating = math.Round(m.AverageRating*float64(scale)*10) / 10
+	}
+}
+
+// joinList stores a list of names, such as genres, in a single column.
+func joinList(list []string) string {
+	return strings.Join(list, ", ")
+}
+
+// splitList is the inverse of joinList.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, ", ")
+}
+
+// moviesQuery builds the SELECT statement and arguments for listing the
+// movies matching the filter in the library of the given owner, or every
+// library if owner is 0. Pages are found using the sort value and id of
+// the last movie on the previous page rather than an offset, so pages
+// stay stable while movies are added or removed.
+func moviesQuery(f service.MovieFilter, owner int64) (string, []interface{}, error) {
+	where := []string{"deleted_at IS NULL", "? IN (0, owner_id)"}
+	if f.Trashed {
+		where[0] = "deleted_at IS NOT NULL"
+	}
+	args := []interface{}{owner}
+
+	if f.TitlePrefix != "" {
+		where = append(where, `title LIKE ? ESCAPE '\'`)
+		args = append(args, escapeLike(f.TitlePrefix)+"%")
+	}
+	if f.Tag != "" {
+		where = append(where, `id IN (
+			SELECT movie_id FROM movie_tags JOIN tags ON tags.id = movie_tags.tag_id
+			WHERE tags.name = ?)`)
+		args = append(args, f.Tag)
+	}
+	if f.Collection != 0 {
+		where = append(where, "id IN (SELECT movie_id FROM collection_movies WHERE collection_id = ?)")
+		args = append(args, f.Collection)
+	}
+
+	// Times are stored as text with the offset they were written in, so
+	// they are compared as Julian day numbers rather than as strings.
+	ranges := []struct {
+		expr string
+		t    time.Time
+	}{
+		{"julianday(created_at) >= julianday(?)", f.CreatedAfter},
+		{"julianday(created_at) < julianday(?)", f.CreatedBefore},
+		{"julianday(updated_at) >= julianday(?)", f.UpdatedAfter},
+		{"julianday(updated_at) < julianday(?)", f.UpdatedBefore},
+		{"julianday(enriched_at) < julianday(?)", f.EnrichedBefore},
+	}
+	for _, r := range ranges {
+		if !r.t.IsZero() {
+			where = append(where, r.expr)
+			args = append(args, r.t)
+		}
+	}
+
+	column, param := "id", "?"
+	switch f.SortBy {
+	case service.SortByTitle:
+		column = "title COLLATE NOCASE"
+	case service.SortByCreatedAt:
+		column, param = "julianday(created_at)", "julianday(?)"
+	case service.SortByUpdatedAt:
+		column, param = "julianday(updated_at)", "julianday(?)"
+	}
+
+	op, dir := ">", "ASC"
+	if f.Desc {
+		op, dir = "<", "DESC"
+	}
+
+	if c := f.After; c != nil {
+		var value interface{}
+		switch f.SortBy {
+		case service.SortByTitle:
+			value = c.Value
+		case service.SortByCreatedAt, service.SortByUpdatedAt:
+			t, err := c.Time()
+			if err != nil {
+				return "", nil, service.ErrInvalidCursor
+			}
+			value = t
+		default:
+			value = c.ID
+		}
+
+		where = append(where, "("+column+" "+op+" "+param+" OR ("+column+" = "+param+" AND id "+op+" ?))")
+		args = append(args, value, value, c.ID)
+	}
+
+	query := `SELECT ` + movieColumns + ` FROM movies`
+	query += " WHERE " + strings.Join(where, " AND ")
+	query += " ORDER BY " + column + " " + dir + ", id " + dir
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit+1)
+	}
+
+	return query + ";", args, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so that it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
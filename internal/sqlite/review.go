@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"../service"
+)
+
+// Ensure ReviewService implements service.ReviewService.
+var _ service.ReviewService = &ReviewService{}
+
+// ReviewService represents a SQLite implementation of a ReviewService.
+type ReviewService struct {
+	DB *sql.DB
+	Timeout
+
+	// Scale is the highest score of new reviews. Existing reviews keep the
+	// scale they were written with. A zero value means
+	// service.DefaultRatingScale.
+	Scale int
+}
+
+// GetReview returns the review of a movie from the database.
+func (s *ReviewService) GetReview(ctx context.Context, movieID int64) (*service.Review, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return nil, err
+	}
+
+	return selectReview(ctx, dbTx, movieID)
+}
+
+// PutReview validates the review and adds it to the database, replacing
+// any existing review of the movie. It reports whether the review was
+// created.
+func (s *ReviewService) PutReview(ctx context.Context, movieID int64, r *service.Review) (bool, error) {
+	r.Scale = s.Scale
+	if r.Scale == 0 {
+		r.Scale = service.DefaultRatingScale
+	}
+	if err := r.Validate(r.Scale); err != nil {
+		return false, err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return false, err
+	}
+
+	r.MovieID = movieID
+	r.UpdatedAt = time.Now()
+
+	current, err := selectReview(ctx, dbTx, movieID)
+	created := err == service.ErrReviewNotFound
+	if created {
+		r.CreatedAt = r.UpdatedAt
+		_, err = dbTx.ExecContext(ctx, `
+			INSERT INTO reviews (movie_id, score, scale, text, watched_on, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $6);
+		`, r.MovieID, r.Score, r.Scale, r.Text, r.WatchedOn, r.CreatedAt)
+	} else if err == nil {
+		r.CreatedAt = current.CreatedAt
+		_, err = dbTx.ExecContext(ctx, `
+			UPDATE reviews
+			SET movie_id = $1, score = $2, scale = $3, text = $4, watched_on = $5, updated_at = $6
+			WHERE movie_id = $1;
+		`, r.MovieID, r.Score, r.Scale, r.Text, r.WatchedOn, r.UpdatedAt)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return created, dbTx.Commit()
+}
+
+// DeleteReview removes the review of a movie from the database.
+func (s *ReviewService) DeleteReview(ctx context.Context, movieID int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return err
+	}
+
+	res, err := dbTx.ExecContext(ctx, `DELETE FROM reviews WHERE movie_id = $1;`, movieID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.ErrReviewNotFound
+	}
+
+	return dbTx.Commit()
+}
+
+// checkMovie returns a not found error unless the movie with the given id
+// exists, is visible to the user in ctx and is not in the trash.
+func checkMovie(ctx context.Context, dbTx *sql.Tx, id int64) error {
+	var exists int
+	err := dbTx.QueryRowContext(ctx, `
+		SELECT 1
+		FROM movies
+		WHERE id = $1 AND $2 IN (0, owner_id) AND deleted_at IS NULL;
+	`, id, service.UserIDFromContext(ctx)).Scan(&exists)
+
+	return movieError(err)
+}
+
+// selectReview returns the review of the movie with the given id, or
+// service.ErrReviewNotFound.
+func selectReview(ctx context.Context, dbTx *sql.Tx, movieID int64) (*service.Review, error) {
+	var r service.Review
+	err := dbTx.QueryRowContext(ctx, `
+		SELECT movie_id, score, scale, text, watched_on, created_at, updated_at
+		FROM reviews
+		WHERE movie_id = $1;
+	`, movieID).Scan(&r.MovieID, &r.Score, &r.Scale, &r.Text, &r.WatchedOn,
+		&r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, service.ErrReviewNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
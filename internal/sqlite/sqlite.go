@@ -1,27 +1,23 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3" // ...
 )
 
 // Start attempts to open a SQLite database or returns an error
-// if opening fails. It also pings the database to test the connection
-// or returns an error if a connection cannot be made. It then
-// creates any tables or returns an error if table creation fails.
+// if opening fails. It then applies any pending schema migrations or
+// returns an error if a migration fails.
 func Start(dataSourceName string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dataSourceName)
-	if err != nil {
-		return nil, err
-	}
-
-	err = db.Ping()
+	db, err := Open(dataSourceName)
 	if err != nil {
 		return nil, err
 	}
 
-	err = initialize(db)
+	err = MigrateUp(db)
 	if err != nil {
 		return nil, err
 	}
@@ -29,40 +25,35 @@ func Start(dataSourceName string) (*sql.DB, error) {
 	return db, nil
 }
 
-// initialize creates the database tables. This is non-destructive if
-// data already exists.
-func initialize(db *sql.DB) error {
-	dbTx, err := db.Begin()
+// Open attempts to open a SQLite database or returns an error
+// if opening fails. It also pings the database to test the connection
+// or returns an error if a connection cannot be made. Unlike Start it
+// does not apply any migrations.
+func Open(dataSourceName string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dataSourceName)
 	if err != nil {
-		dbTx.Rollback()
-		return err
+		return nil, err
 	}
 
-	// Create the movies table.
-	if err = moviesTable(dbTx); err != nil {
-		dbTx.Rollback()
-		return err
+	err = db.Ping()
+	if err != nil {
+		return nil, err
 	}
 
-	return dbTx.Commit()
+	return db, nil
 }
 
-// moviesTable defines and creates a new movies database table if
-// one doesn't already exist.
-func moviesTable(db *sql.Tx) error {
-	stmt := `
-		CREATE TABLE IF NOT EXISTS movies(
-			id INTEGER PRIMARY KEY NOT NULL,
-			title VARCHAR(255) NOT NULL,
-			imdb_id VARCHAR(255) UNIQUE NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+// Timeout bounds how long a single call to a service may spend in the
+// database. A zero value means calls are only bounded by their context.
+// Services embed it and wrap the context of every call with it.
+type Timeout time.Duration
 
-			CHECK (length(title) > 0 AND length(imdb_id) > 0)
-		);
-	`
-
-	_, err := db.Exec(stmt)
+// context returns a copy of ctx that is cancelled after the timeout
+// elapses. If no timeout is set ctx is returned unchanged.
+func (t Timeout) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t <= 0 {
+		return ctx, func() {}
+	}
 
-	return err
+	return context.WithTimeout(ctx, time.Duration(t))
 }
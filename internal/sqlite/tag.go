@@ -0,0 +1,283 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"../service"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Ensure TagService implements service.TagService.
+var _ service.TagService = &TagService{}
+
+// TagService represents a SQLite implementation of a TagService.
+type TagService struct {
+	DB *sql.DB
+	Timeout
+}
+
+// tagColumns lists the columns read by scanTag, in order. Movies in the
+// trash are left out of the movie count.
+const tagColumns = `
+	tags.id, tags.name, tags.created_at,
+	(SELECT COUNT(*)
+		FROM movie_tags JOIN movies ON movies.id = movie_tags.movie_id
+		WHERE movie_tags.tag_id = tags.id AND movies.deleted_at IS NULL)`
+
+// GetTags returns the tags from the database, ordered by name.
+func (s *TagService) GetTags(ctx context.Context) (*service.Tags, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT `+tagColumns+`
+		FROM tags
+		WHERE $1 IN (0, owner_id)
+		ORDER BY name, id;
+	`, service.UserIDFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := service.Tags{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &tags, nil
+}
+
+// GetTag returns a single tag from the database.
+func (s *TagService) GetTag(ctx context.Context, id int64) (*service.Tag, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	row := s.DB.QueryRowContext(ctx, `
+		SELECT `+tagColumns+`
+		FROM tags
+		WHERE id = $1 AND $2 IN (0, owner_id);
+	`, id, service.UserIDFromContext(ctx))
+
+	tag, err := scanTag(row)
+	if err != nil {
+		return nil, tagError(err)
+	}
+
+	return tag, nil
+}
+
+// CreateTag validates the tag and adds it to the library of the user in
+// ctx.
+func (s *TagService) CreateTag(ctx context.Context, t *service.Tag) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	t.CreatedAt = time.Now()
+	t.MovieCount = 0
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	res, err := s.DB.ExecContext(ctx, `
+		INSERT INTO tags (owner_id, name, created_at)
+		VALUES ($1, $2, $3);
+	`, service.UserIDFromContext(ctx), t.Name, t.CreatedAt)
+	if err != nil {
+		return tagError(err)
+	}
+
+	t.ID, err = res.LastInsertId()
+
+	return err
+}
+
+// UpdateTag validates the tag and renames the tag with the given id to
+// its name. The tag is filled in from the database.
+func (s *TagService) UpdateTag(ctx context.Context, id int64, t *service.Tag) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE tags
+		SET name = $1
+		WHERE id = $2 AND $3 IN (0, owner_id);
+	`, t.Name, id, service.UserIDFromContext(ctx))
+	if err != nil {
+		return tagError(err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.ErrTagNotFound
+	}
+
+	current, err := s.GetTag(ctx, id)
+	if err != nil {
+		return err
+	}
+	*t = *current
+
+	return nil
+}
+
+// DeleteTag removes a tag, and the tag from its movies, from the database.
+func (s *TagService) DeleteTag(ctx context.Context, id int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	res, err := dbTx.ExecContext(ctx, `
+		DELETE FROM tags
+		WHERE id = $1 AND $2 IN (0, owner_id);
+	`, id, service.UserIDFromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.ErrTagNotFound
+	}
+
+	if _, err := dbTx.ExecContext(ctx, `DELETE FROM movie_tags WHERE tag_id = $1;`, id); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+// TagMovie gives a movie the tag with the given id in the database.
+func (s *TagService) TagMovie(ctx context.Context, id, movieID int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMember(ctx, dbTx, "tags", id, movieID); err != nil {
+		return tagError(err)
+	}
+
+	if _, err := dbTx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO movie_tags (movie_id, tag_id)
+		VALUES ($1, $2);
+	`, movieID, id); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+// UntagMovie removes the tag with the given id from a movie in the
+// database.
+func (s *TagService) UntagMovie(ctx context.Context, id, movieID int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMember(ctx, dbTx, "tags", id, movieID); err != nil {
+		return tagError(err)
+	}
+
+	res, err := dbTx.ExecContext(ctx, `
+		DELETE FROM movie_tags
+		WHERE movie_id = $1 AND tag_id = $2;
+	`, movieID, id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.Errorf(service.ENotFound, "the movie does not have the tag")
+	}
+
+	return dbTx.Commit()
+}
+
+// checkMember returns sql.ErrNoRows unless the row of the given table,
+// tags or collections, with the given id is visible to the user in ctx,
+// and a not found error unless the movie with the given id is visible, in
+// the same library and not in the trash.
+func checkMember(ctx context.Context, dbTx *sql.Tx, table string, id, movieID int64) error {
+	var owner int64
+	if err := dbTx.QueryRowContext(ctx, `
+		SELECT owner_id
+		FROM `+table+`
+		WHERE id = $1 AND $2 IN (0, owner_id);
+	`, id, service.UserIDFromContext(ctx)).Scan(&owner); err != nil {
+		return err
+	}
+
+	var exists int
+	err := dbTx.QueryRowContext(ctx, `
+		SELECT 1
+		FROM movies
+		WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL;
+	`, movieID, owner).Scan(&exists)
+
+	return movieError(err)
+}
+
+// tagError returns a service error for errors caused by a missing tag or
+// a tag name already in use, and err otherwise.
+func tagError(err error) error {
+	if err == sql.ErrNoRows {
+		return service.ErrTagNotFound
+	}
+
+	var e sqlite3.Error
+	if errors.As(err, &e) && e.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return &service.Error{
+			Code:    service.EConflict,
+			Message: "a tag with that name already exists",
+			Fields:  service.Validation{"name": "is already taken"},
+			Err:     err,
+		}
+	}
+
+	return err
+}
+
+// scanTag scans a row selected with tagColumns into a tag.
+func scanTag(row scanner) (*service.Tag, error) {
+	var t service.Tag
+	if err := row.Scan(&t.ID, &t.Name, &t.CreatedAt, &t.MovieCount); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
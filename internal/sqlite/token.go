@@ -0,0 +1,180 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"time"
+
+	"../service"
+)
+
+// Ensure TokenService implements service.TokenService.
+var _ service.TokenService = &TokenService{}
+
+// TokenService represents a SQLite implementation of a TokenService.
+// Tokens are stored as SHA-256 hashes, like session tokens.
+type TokenService struct {
+	DB *sql.DB
+	Timeout
+}
+
+// tokenColumns lists the columns read by scanToken, in order.
+const tokenColumns = `
+	api_tokens.id, user_id, name, scope, prefix, api_tokens.created_at,
+	expires_at, last_used_at, revoked_at`
+
+// GetTokens returns every token of a user from the database, including
+// expired and revoked ones, newest first.
+func (s *TokenService) GetTokens(ctx context.Context, userID int64) (*service.Tokens, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT `+tokenColumns+`
+		FROM api_tokens
+		WHERE user_id = $1
+		ORDER BY id DESC;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := service.Tokens{}
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &tokens, nil
+}
+
+// CreateToken adds a new token to the database and returns its secret.
+func (s *TokenService) CreateToken(ctx context.Context, t *service.Token) (string, error) {
+	if err := t.Validate(); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := service.TokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	t.Prefix = secret[:len(service.TokenPrefix)+4]
+	t.CreatedAt = time.Now()
+
+	res, err := s.DB.ExecContext(ctx, `
+		INSERT INTO api_tokens (user_id, name, scope, prefix, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);
+	`, t.UserID, t.Name, t.Scope, t.Prefix, hashToken(secret), t.CreatedAt, t.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	if t.ID, err = res.LastInsertId(); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// RevokeToken stops a token of a user from being used. Revoking a token
+// twice does nothing.
+func (s *TokenService) RevokeToken(ctx context.Context, userID, id int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE api_tokens
+		SET revoked_at = COALESCE(revoked_at, $1)
+		WHERE id = $2 AND user_id = $3;
+	`, time.Now(), id, userID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.Errorf(service.ENotFound, "token not found")
+	}
+
+	return nil
+}
+
+// AuthenticateToken returns the token with the given secret and the user
+// it belongs to, recording that it was used, or service.ErrInvalidToken.
+func (s *TokenService) AuthenticateToken(ctx context.Context, secret string) (*service.User, *service.Token, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	var user service.User
+	row := s.DB.QueryRowContext(ctx, `
+		SELECT `+tokenColumns+`, users.username, users.created_at, users.updated_at
+		FROM api_tokens
+		JOIN users ON users.id = api_tokens.user_id
+		WHERE token_hash = $1;
+	`, hashToken(secret))
+
+	token, err := scanToken(row, &user.Username, &user.CreatedAt, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, service.ErrInvalidToken
+	} else if err != nil {
+		return nil, nil, err
+	}
+	user.ID = token.UserID
+
+	now := time.Now()
+	if !token.Active(now) {
+		return nil, nil, service.ErrInvalidToken
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `
+		UPDATE api_tokens
+		SET id = $1, last_used_at = $2
+		WHERE id = $1;
+	`, token.ID, now); err != nil {
+		return nil, nil, err
+	}
+	token.LastUsedAt = &now
+
+	return &user, token, nil
+}
+
+// scanToken scans a row selected with tokenColumns into a token. Any
+// extra destinations are scanned from the columns that follow.
+func scanToken(row scanner, extra ...interface{}) (*service.Token, error) {
+	var token service.Token
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+
+	dest := []interface{}{&token.ID, &token.UserID, &token.Name, &token.Scope,
+		&token.Prefix, &token.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,269 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"../service"
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ensure UserService implements service.UserService.
+var _ service.UserService = &UserService{}
+
+// UserService represents a SQLite implementation of a UserService.
+// Passwords are stored as bcrypt hashes and session tokens as SHA-256
+// hashes, so a copy of the database cannot be used to sign in.
+type UserService struct {
+	DB *sql.DB
+	Timeout
+}
+
+// dummyHash is compared against when a username does not exist, so that
+// signing in takes as long for unknown users as for wrong passwords.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("pmdb-dummy-password"), bcrypt.DefaultCost)
+
+// GetUser returns a single user from the database.
+func (s *UserService) GetUser(ctx context.Context, id int64) (*service.User, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	user, _, err := s.selectUser(ctx, "id = $1", id)
+	return user, err
+}
+
+// GetUserByUsername returns the user with the given username, ignoring
+// case, from the database.
+func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*service.User, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	user, _, err := s.selectUser(ctx, "username = $1", strings.TrimSpace(username))
+	return user, err
+}
+
+// CreateUser adds a new user to the database. The first user also takes
+// over the movies added before there were any users.
+func (s *UserService) CreateUser(ctx context.Context, username, password string) (int64, error) {
+	username = strings.TrimSpace(username)
+	if err := service.ValidateCredentials(username, password); err != nil {
+		return 0, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer dbTx.Rollback()
+
+	res, err := dbTx.ExecContext(ctx, `
+		INSERT INTO users (username, password_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $3);
+	`, username, string(hash), time.Now())
+	if err != nil {
+		return 0, userError(err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, table := range []string{"movies", "movie_history"} {
+		if _, err := dbTx.ExecContext(ctx, `
+			UPDATE `+table+`
+			SET owner_id = $1
+			WHERE owner_id = 0 AND $1 = (SELECT MIN(id) FROM users);
+		`, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, dbTx.Commit()
+}
+
+// SetPassword replaces the password of an existing user in the database
+// and signs them out of every session.
+func (s *UserService) SetPassword(ctx context.Context, id int64, password string) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := service.ValidateCredentials(user.Username, password); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if _, err := dbTx.ExecContext(ctx, `
+		UPDATE users
+		SET id = $1, password_hash = $2, updated_at = $3
+		WHERE id = $1;
+	`, id, string(hash), time.Now()); err != nil {
+		return err
+	}
+
+	if _, err := dbTx.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1;`, id); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+// Authenticate returns the user with the given username if password is
+// theirs, or service.ErrInvalidCredentials.
+func (s *UserService) Authenticate(ctx context.Context, username, password string) (*service.User, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	user, hash, err := s.selectUser(ctx, "username = $1", strings.TrimSpace(username))
+	if service.ErrorCode(err) == service.ENotFound {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, service.ErrInvalidCredentials
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		return nil, service.ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// CreateSession starts a new session for a user that expires after
+// maxAge. Expired sessions of every user are removed at the same time.
+func (s *UserService) CreateSession(ctx context.Context, userID int64, maxAge time.Duration) (*service.Session, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &service.Session{
+		Token:     base64.RawURLEncoding.EncodeToString(b),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(maxAge),
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= $1;`, now); err != nil {
+		return nil, err
+	}
+
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4);
+	`, hashToken(session.Token), session.UserID, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// GetSessionUser returns the user signed in with a session token, or
+// service.ErrSessionNotFound if the session is unknown or expired.
+func (s *UserService) GetSessionUser(ctx context.Context, token string) (*service.User, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	var user service.User
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT users.id, users.username, users.created_at, users.updated_at
+		FROM sessions
+		JOIN users ON users.id = sessions.user_id
+		WHERE sessions.token_hash = $1 AND sessions.expires_at > $2;
+	`, hashToken(token), time.Now()).Scan(&user.ID, &user.Username, &user.CreatedAt, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, service.ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// DeleteSession ends a session. Ending an unknown session does nothing.
+func (s *UserService) DeleteSession(ctx context.Context, token string) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = $1;`, hashToken(token))
+	return err
+}
+
+// selectUser returns the user matching the WHERE condition along with
+// their password hash.
+func (s *UserService) selectUser(ctx context.Context, where string, args ...interface{}) (*service.User, []byte, error) {
+	var user service.User
+	var hash string
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT id, username, password_hash, created_at, updated_at
+		FROM users
+		WHERE `+where+`;
+	`, args...).Scan(&user.ID, &user.Username, &hash, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, nil, userError(err)
+	}
+
+	return &user, []byte(hash), nil
+}
+
+// hashToken returns the hex encoded SHA-256 hash of a session token,
+// which is what the database stores.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// userError translates errors from the users table into service errors.
+// Any other error is returned unchanged.
+func userError(err error) error {
+	if err == sql.ErrNoRows {
+		return service.Errorf(service.ENotFound, "user not found")
+	}
+
+	var e sqlite3.Error
+	if errors.As(err, &e) && e.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return &service.Error{
+			Code:    service.EConflict,
+			Message: "a user with that username already exists",
+			Fields:  service.Validation{"username": "is already taken"},
+			Err:     err,
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,167 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"../service"
+)
+
+// Ensure ViewingService implements service.ViewingService.
+var _ service.ViewingService = &ViewingService{}
+
+// ViewingService represents a SQLite implementation of a ViewingService.
+type ViewingService struct {
+	DB *sql.DB
+	Timeout
+}
+
+// viewingColumns lists the columns read by scanViewing, in order.
+const viewingColumns = `
+	viewings.id, movie_id, watched_on, location, medium, notes,
+	viewings.created_at`
+
+// GetViewings returns the viewings of a movie from the database, most
+// recent first.
+func (s *ViewingService) GetViewings(ctx context.Context, movieID int64) (*service.Viewings, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return nil, err
+	}
+
+	rows, err := dbTx.QueryContext(ctx, `
+		SELECT `+viewingColumns+`
+		FROM viewings
+		WHERE movie_id = $1
+		ORDER BY watched_on DESC, id DESC;
+	`, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	viewings := service.Viewings{}
+	for rows.Next() {
+		viewing, err := scanViewing(rows)
+		if err != nil {
+			return nil, err
+		}
+		viewings = append(viewings, viewing)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &viewings, nil
+}
+
+// CreateViewing validates the viewing and adds it to the database.
+func (s *ViewingService) CreateViewing(ctx context.Context, movieID int64, v *service.Viewing) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := insertViewing(ctx, dbTx, movieID, v); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+// GetDiary returns the viewings logged in a year from the database, most
+// recent first.
+func (s *ViewingService) GetDiary(ctx context.Context, year int) (*service.Diary, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT `+viewingColumns+`, movies.title, movies.imdb_id
+		FROM viewings
+		JOIN movies ON movies.id = viewings.movie_id
+		WHERE watched_on >= $1 AND watched_on < $2
+			AND movies.deleted_at IS NULL AND $3 IN (0, movies.owner_id)
+		ORDER BY watched_on DESC, viewings.id DESC;
+	`, fmt.Sprintf("%04d-01-01", year), fmt.Sprintf("%04d-01-01", year+1),
+		service.UserIDFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	diary := service.Diary{}
+	for rows.Next() {
+		var entry service.DiaryEntry
+		entry.Viewing, err = scanViewing(rows, &entry.Title, &entry.ImdbID)
+		if err != nil {
+			return nil, err
+		}
+		diary = append(diary, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &diary, nil
+}
+
+// insertViewing validates a viewing of the movie with the given id, which
+// must be visible to the user in ctx, and inserts it. A viewing without a
+// date is logged today.
+func insertViewing(ctx context.Context, dbTx *sql.Tx, movieID int64, v *service.Viewing) error {
+	if v.WatchedOn == "" {
+		v.WatchedOn = time.Now().Format(service.DateLayout)
+	}
+	if err := v.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return err
+	}
+
+	v.MovieID = movieID
+	v.CreatedAt = time.Now()
+
+	res, err := dbTx.ExecContext(ctx, `
+		INSERT INTO viewings (movie_id, watched_on, location, medium, notes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`, v.MovieID, v.WatchedOn, v.Location, v.Medium, v.Notes, v.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	v.ID, err = res.LastInsertId()
+
+	return err
+}
+
+// scanViewing scans a row selected with viewingColumns into a viewing.
+// Any extra destinations are scanned from the columns that follow.
+func scanViewing(row scanner, extra ...interface{}) (*service.Viewing, error) {
+	var v service.Viewing
+
+	dest := []interface{}{&v.ID, &v.MovieID, &v.WatchedOn, &v.Location,
+		&v.Medium, &v.Notes, &v.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
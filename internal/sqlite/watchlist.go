@@ -0,0 +1,303 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"../service"
+)
+
+// Ensure WatchlistService implements service.WatchlistService.
+var _ service.WatchlistService = &WatchlistService{}
+
+// WatchlistService represents a SQLite implementation of a
+// WatchlistService.
+type WatchlistService struct {
+	DB *sql.DB
+	Timeout
+}
+
+// watchlistColumns lists the columns read by scanWatchlistEntry, in order.
+const watchlistColumns = `
+	watchlist.movie_id, movies.title, movies.imdb_id, status, position,
+	watchlist.created_at, watchlist.updated_at`
+
+// GetWatchlist returns the watchlist from the database, only holding the
+// movies with the given status unless it is empty.
+func (s *WatchlistService) GetWatchlist(ctx context.Context, status string) (*service.Watchlist, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT `+watchlistColumns+`
+		FROM watchlist
+		JOIN movies ON movies.id = watchlist.movie_id
+		WHERE movies.deleted_at IS NULL AND $1 IN (0, movies.owner_id)
+			AND $2 IN ('', status)
+		ORDER BY position, watchlist.movie_id;
+	`, service.UserIDFromContext(ctx), status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	watchlist := service.Watchlist{}
+	for rows.Next() {
+		entry, err := scanWatchlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		watchlist = append(watchlist, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &watchlist, nil
+}
+
+// GetWatchlistEntry returns the watchlist entry of a movie from the
+// database.
+func (s *WatchlistService) GetWatchlistEntry(ctx context.Context, movieID int64) (*service.WatchlistEntry, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return nil, err
+	}
+
+	return selectWatchlistEntry(ctx, dbTx, movieID)
+}
+
+// PutWatchlistEntry validates the entry and sets the status of the movie
+// on the watchlist, adding it to the end of the watchlist if it is not on
+// it. The entry is filled in from the database.
+func (s *WatchlistService) PutWatchlistEntry(ctx context.Context, movieID int64, e *service.WatchlistEntry) (bool, error) {
+	if err := e.Validate(); err != nil {
+		return false, err
+	}
+
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return false, err
+	}
+
+	created, err := setStatus(ctx, dbTx, movieID, e.Status)
+	if err != nil {
+		return false, err
+	}
+
+	current, err := selectWatchlistEntry(ctx, dbTx, movieID)
+	if err != nil {
+		return false, err
+	}
+	*e = *current
+
+	return created, dbTx.Commit()
+}
+
+// DeleteWatchlistEntry removes a movie from the watchlist in the database.
+func (s *WatchlistService) DeleteWatchlistEntry(ctx context.Context, movieID int64) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := checkMovie(ctx, dbTx, movieID); err != nil {
+		return err
+	}
+
+	res, err := dbTx.ExecContext(ctx, `DELETE FROM watchlist WHERE movie_id = $1;`, movieID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return service.ErrNotOnWatchlist
+	}
+
+	return dbTx.Commit()
+}
+
+// ReorderWatchlist moves the given movies to the top of the watchlist in
+// the database, in the given order, and numbers every entry from 1. It
+// returns the reordered watchlist.
+func (s *WatchlistService) ReorderWatchlist(ctx context.Context, movieIDs []int64) (*service.Watchlist, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	rows, err := dbTx.QueryContext(ctx, `
+		SELECT watchlist.movie_id
+		FROM watchlist
+		JOIN movies ON movies.id = watchlist.movie_id
+		WHERE movies.deleted_at IS NULL AND $1 IN (0, movies.owner_id)
+		ORDER BY position, watchlist.movie_id;
+	`, service.UserIDFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var current []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Every given movie must be on the watchlist, and only given once.
+	moved := make(map[int64]bool)
+	for _, id := range current {
+		moved[id] = false
+	}
+	for _, id := range movieIDs {
+		if done, ok := moved[id]; !ok {
+			return nil, service.Errorf(service.EInvalid, "movie %d is not on the watchlist", id)
+		} else if done {
+			return nil, service.Errorf(service.EInvalid, "movie %d is given more than once", id)
+		}
+		moved[id] = true
+	}
+
+	order := append([]int64{}, movieIDs...)
+	for _, id := range current {
+		if !moved[id] {
+			order = append(order, id)
+		}
+	}
+
+	for i, id := range order {
+		if _, err := dbTx.ExecContext(ctx, `
+			UPDATE watchlist
+			SET position = $1
+			WHERE movie_id = $2;
+		`, i+1, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.GetWatchlist(ctx, "")
+}
+
+// MarkWatched sets the status of a movie on the watchlist to watched,
+// adding it if it is not on it, and logs the viewing in the database.
+func (s *WatchlistService) MarkWatched(ctx context.Context, movieID int64, v *service.Viewing) error {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	dbTx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := insertViewing(ctx, dbTx, movieID, v); err != nil {
+		return err
+	}
+
+	if _, err := setStatus(ctx, dbTx, movieID, service.StatusWatched); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+// setStatus sets the status of a movie on the watchlist, adding it after
+// the last movie on the watchlist of the user in ctx if it is not on it,
+// and reports whether it was added.
+func setStatus(ctx context.Context, dbTx *sql.Tx, movieID int64, status string) (bool, error) {
+	now := time.Now()
+
+	res, err := dbTx.ExecContext(ctx, `
+		UPDATE watchlist
+		SET movie_id = $1, status = $2, updated_at = $3
+		WHERE movie_id = $1;
+	`, movieID, status, now)
+	if err != nil {
+		return false, err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if n > 0 {
+		return false, nil
+	}
+
+	_, err = dbTx.ExecContext(ctx, `
+		INSERT INTO watchlist (movie_id, status, position, created_at, updated_at)
+		SELECT $1, $2, COALESCE(MAX(position), 0) + 1, $3, $3
+		FROM watchlist
+		JOIN movies ON movies.id = watchlist.movie_id
+		WHERE $4 IN (0, movies.owner_id);
+	`, movieID, status, now, service.UserIDFromContext(ctx))
+
+	return err == nil, err
+}
+
+// selectWatchlistEntry returns the watchlist entry of the movie with the
+// given id, or service.ErrNotOnWatchlist.
+func selectWatchlistEntry(ctx context.Context, dbTx *sql.Tx, movieID int64) (*service.WatchlistEntry, error) {
+	row := dbTx.QueryRowContext(ctx, `
+		SELECT `+watchlistColumns+`
+		FROM watchlist
+		JOIN movies ON movies.id = watchlist.movie_id
+		WHERE watchlist.movie_id = $1;
+	`, movieID)
+
+	entry, err := scanWatchlistEntry(row)
+	if err == sql.ErrNoRows {
+		return nil, service.ErrNotOnWatchlist
+	}
+
+	return entry, err
+}
+
+// scanWatchlistEntry scans a row selected with watchlistColumns into a
+// watchlist entry.
+func scanWatchlistEntry(row scanner) (*service.WatchlistEntry, error) {
+	var e service.WatchlistEntry
+	if err := row.Scan(&e.MovieID, &e.Title, &e.ImdbID, &e.Status,
+		&e.Position, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}